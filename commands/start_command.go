@@ -17,6 +17,7 @@ func GetStartCommand() *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			config, _ := cmd.Flags().GetString("filename")
 			verbosity, _ := cmd.Flags().GetString("verbosity")
+			watchInterval, _ := cmd.Flags().GetDuration("watch-interval")
 
 			if verbosity == "" {
 				verbosity = os.Getenv("KERMOO_VERBOSITY")
@@ -30,16 +31,20 @@ func GetStartCommand() *cobra.Command {
 
 			user_config.MustLoadPreparedConfig(config)
 
+			// Signals are caught from now on, as starting might take a while on process delays
+			signals := user_config.NotifyShutdownSignals()
+
 			user_config.Prepared.Start()
 
-			for {
-				logger.Log.Info("app is alive")
-				time.Sleep(1 * time.Minute)
-			}
+			user_config.StartReloader(config)
+			user_config.StartFileWatcher(config, watchInterval)
+
+			os.Exit(user_config.WaitForShutdown(signals))
 		},
 	}
 
 	cmd.Flags().StringP("filename", "f", "", "(Deprecated) Content of config or path to file. Use [CONFIG] placeholder argument instead. It will be removed in future versions.")
+	cmd.Flags().Duration("watch-interval", 5*time.Second, "Interval of checking the config file for changes to reload it. Use 0 to disable it. Config is also reloaded on SIGHUP signal.")
 	cmd.Flags().StringP("verbosity", "v", "", "Verbosity level of logging output, including: debug, info, warning, error, fatal. It overrides KERMOO_VERBOSITY environment variable.")
 
 	return cmd
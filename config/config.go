@@ -15,9 +15,15 @@ type WebServerDefault struct {
 	Port      int32
 }
 
+type AdminDefault struct {
+	Interface string
+	Port      int32
+}
+
 type DefaultTemplate struct {
 	Planner   PlannerDefault
 	WebServer WebServerDefault
+	Admin     AdminDefault
 }
 
 var (
@@ -34,6 +40,10 @@ var (
 			Port:      80,
 			Interface: "0.0.0.0",
 		},
+		Admin: AdminDefault{
+			Port:      9999,
+			Interface: "0.0.0.0",
+		},
 	}
 )
 
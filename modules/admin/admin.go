@@ -0,0 +1,380 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"kermoo/config"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type Admin struct {
+	// Interface defines the network interface which the admin server should listen
+	// on. Default is 0.0.0.0 but you're free to define another one like 127.0.0.1.
+	Interface *string `json:"interface"`
+
+	// Port defines the port which the admin server should listen on. It must be different
+	// from the ports of the web servers. Default is 9999.
+	Port *int32 `json:"port"`
+
+	plans      []*planner.Plan
+	collectors []metrics.Collector
+	mu         sync.RWMutex
+	server     *http.Server
+}
+
+type PlanStatus struct {
+	Name      string          `json:"name"`
+	State     string          `json:"state"`
+	Dedicated bool            `json:"dedicated"`
+	SubPlan   int             `json:"sub_plan"`
+	SubPlans  int             `json:"sub_plans"`
+	Cycle     uint64          `json:"cycle"`
+	Value     *CycleStatus    `json:"value"`
+	Override  *OverrideStatus `json:"override"`
+}
+
+type CycleStatus struct {
+	Percentage float64 `json:"percentage"`
+	Size       int64   `json:"size"`
+	Latency    string  `json:"latency"`
+	Succeeding *bool   `json:"succeeding"`
+}
+
+type OverrideStatus struct {
+	Percentage *float64 `json:"percentage"`
+	Size       *int64   `json:"size"`
+	Latency    *string  `json:"latency"`
+	Until      string   `json:"until"`
+}
+
+type OverrideRequest struct {
+	// Percentage optionally forces the percentage of the plan.
+	Percentage *fluent.FluentFloat `json:"percentage"`
+
+	// Size optionally forces the size of the plan.
+	Size *fluent.FluentSize `json:"size"`
+
+	// Latency optionally forces the latency of the plan.
+	Latency *fluent.FluentDuration `json:"latency"`
+
+	// Duration determines how long the forced values should last.
+	Duration *fluent.FluentDuration `json:"duration"`
+}
+
+func (a *Admin) GetName() string {
+	return "admin"
+}
+
+func (a *Admin) GetPort() int32 {
+	if a.Port != nil {
+		return *a.Port
+	}
+
+	return config.Default.Admin.Port
+}
+
+func (a *Admin) GetInterface() string {
+	if a.Interface != nil {
+		return *a.Interface
+	}
+
+	return config.Default.Admin.Interface
+}
+
+func (a *Admin) GetAddress() string {
+	return fmt.Sprintf("%s:%d", a.GetInterface(), a.GetPort())
+}
+
+func (a *Admin) Validate() error {
+	if a.GetPort() <= 0 || a.GetPort() > 65535 {
+		return fmt.Errorf("port %d is out of range", a.GetPort())
+	}
+
+	return nil
+}
+
+// SetPlans sets the plans which can be controlled through the admin server.
+func (a *Admin) SetPlans(plans []*planner.Plan) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.plans = plans
+}
+
+func (a *Admin) getPlans() []*planner.Plan {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.plans
+}
+
+// SetCollectors sets the modules whose metrics are exposed through the admin server.
+func (a *Admin) SetCollectors(collectors []metrics.Collector) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.collectors = collectors
+}
+
+func (a *Admin) getCollectors() []metrics.Collector {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.collectors
+}
+
+func (a *Admin) GetRouter() *mux.Router {
+	r := mux.NewRouter()
+
+	r.HandleFunc("/plans", a.handleListPlans).Methods("GET")
+	r.HandleFunc("/plans/{name}", a.handleGetPlan).Methods("GET")
+	r.HandleFunc("/plans/{name}/pause", a.handlePausePlan).Methods("POST")
+	r.HandleFunc("/plans/{name}/resume", a.handleResumePlan).Methods("POST")
+	r.HandleFunc("/plans/{name}/override", a.handleOverridePlan).Methods("POST")
+	r.HandleFunc("/plans/{name}/override", a.handleClearOverride).Methods("DELETE")
+	r.HandleFunc("/plans/{name}/skip", a.handleSkipSubPlan).Methods("POST")
+	r.HandleFunc("/metrics", a.handleMetrics).Methods("GET")
+
+	return r
+}
+
+func (a *Admin) ListenOnBackground() error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	a.server = &http.Server{
+		Addr:    a.GetAddress(),
+		Handler: a.GetRouter(),
+	}
+
+	go func() {
+		logger.Log.Info("listening admin server...", zap.String("address", a.server.Addr))
+
+		if err := a.server.ListenAndServe(); err != nil {
+			if err != http.ErrServerClosed {
+				logger.Log.Fatal(
+					"failed on listening and serving admin server",
+					zap.Error(err),
+					zap.String("address", a.server.Addr),
+				)
+			} else {
+				logger.Log.Info("admin server is down", zap.NamedError("reason", err))
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (a *Admin) Stop() error {
+	logger.Log.Info("shutting down admin server...")
+	if a.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	return a.server.Shutdown(ctx)
+}
+
+func (a *Admin) findPlan(name string) *planner.Plan {
+	for _, plan := range a.getPlans() {
+		if plan.Name != nil && *plan.Name == name {
+			return plan
+		}
+	}
+
+	return nil
+}
+
+func (a *Admin) handleListPlans(w http.ResponseWriter, r *http.Request) {
+	statuses := []PlanStatus{}
+
+	for _, plan := range a.getPlans() {
+		statuses = append(statuses, makePlanStatus(plan))
+	}
+
+	writeJson(w, http.StatusOK, statuses)
+}
+
+func (a *Admin) handleGetPlan(w http.ResponseWriter, r *http.Request) {
+	a.withPlan(w, r, func(plan *planner.Plan) {
+		writeJson(w, http.StatusOK, makePlanStatus(plan))
+	})
+}
+
+func (a *Admin) handlePausePlan(w http.ResponseWriter, r *http.Request) {
+	a.withPlan(w, r, func(plan *planner.Plan) {
+		logger.Log.Info("pausing plan by admin request", zap.String("plan", *plan.Name))
+		plan.Pause()
+		writeJson(w, http.StatusOK, makePlanStatus(plan))
+	})
+}
+
+func (a *Admin) handleResumePlan(w http.ResponseWriter, r *http.Request) {
+	a.withPlan(w, r, func(plan *planner.Plan) {
+		logger.Log.Info("resuming plan by admin request", zap.String("plan", *plan.Name))
+		plan.Resume()
+		writeJson(w, http.StatusOK, makePlanStatus(plan))
+	})
+}
+
+func (a *Admin) handleOverridePlan(w http.ResponseWriter, r *http.Request) {
+	a.withPlan(w, r, func(plan *planner.Plan) {
+		req := OverrideRequest{}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unable to decode override request: %v", err))
+			return
+		}
+
+		override, err := req.ToCycleValueOverride()
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		logger.Log.Info("overriding plan by admin request", zap.String("plan", *plan.Name), zap.Time("until", override.Until))
+		plan.Override(*override)
+		writeJson(w, http.StatusOK, makePlanStatus(plan))
+	})
+}
+
+func (a *Admin) handleClearOverride(w http.ResponseWriter, r *http.Request) {
+	a.withPlan(w, r, func(plan *planner.Plan) {
+		logger.Log.Info("clearing plan override by admin request", zap.String("plan", *plan.Name))
+		plan.ClearOverride()
+		writeJson(w, http.StatusOK, makePlanStatus(plan))
+	})
+}
+
+func (a *Admin) handleSkipSubPlan(w http.ResponseWriter, r *http.Request) {
+	a.withPlan(w, r, func(plan *planner.Plan) {
+		if err := plan.SkipSubPlan(); err != nil {
+			writeError(w, http.StatusConflict, err)
+			return
+		}
+
+		logger.Log.Info("skipping sub-plan by admin request", zap.String("plan", *plan.Name))
+		writeJson(w, http.StatusOK, makePlanStatus(plan))
+	})
+}
+
+func (a *Admin) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := metrics.Gather(a.getCollectors()).WriteTo(w); err != nil {
+		logger.Log.Error("unable to write metrics response", zap.Error(err))
+	}
+}
+
+func (a *Admin) withPlan(w http.ResponseWriter, r *http.Request, handler func(plan *planner.Plan)) {
+	name := mux.Vars(r)["name"]
+	plan := a.findPlan(name)
+
+	if plan == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("plan %s not found", name))
+		return
+	}
+
+	handler(plan)
+}
+
+func (req *OverrideRequest) ToCycleValueOverride() (*planner.CycleValueOverride, error) {
+	if req.Duration == nil {
+		return nil, fmt.Errorf("duration of the override is required")
+	}
+
+	if req.Percentage == nil && req.Size == nil && req.Latency == nil {
+		return nil, fmt.Errorf("at least one of percentage, size or latency is required")
+	}
+
+	override := planner.CycleValueOverride{
+		Until: time.Now().Add(req.Duration.Get()),
+	}
+
+	if req.Percentage != nil {
+		percentage := req.Percentage.Get()
+		override.Percentage = &percentage
+	}
+
+	if req.Size != nil {
+		size := req.Size.Get()
+		override.Size = &size
+	}
+
+	if req.Latency != nil {
+		latency := req.Latency.Get()
+		override.Latency = &latency
+	}
+
+	return &override, nil
+}
+
+func makePlanStatus(plan *planner.Plan) PlanStatus {
+	subPlan, subPlans := plan.GetSubPlanIndex()
+
+	status := PlanStatus{
+		Name:      *plan.Name,
+		State:     plan.GetState(),
+		Dedicated: plan.IsDedicated(),
+		SubPlan:   subPlan,
+		SubPlans:  subPlans,
+		Cycle:     plan.GetCycleIndex(),
+	}
+
+	if cv := plan.GetCurrentValue(); cv != nil {
+		status.Value = &CycleStatus{
+			Percentage: cv.Percentage,
+			Size:       cv.Size,
+			Latency:    cv.Latency.String(),
+			Succeeding: cv.ComputedPercentageChance,
+		}
+	}
+
+	if override := plan.GetOverride(); override != nil {
+		status.Override = &OverrideStatus{
+			Percentage: override.Percentage,
+			Size:       override.Size,
+			Until:      override.Until.Format(time.RFC3339Nano),
+		}
+
+		if override.Latency != nil {
+			latency := override.Latency.String()
+			status.Override.Latency = &latency
+		}
+	}
+
+	return status
+}
+
+func writeJson(w http.ResponseWriter, status int, content any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	j := json.NewEncoder(w)
+	j.SetIndent("", "  ")
+
+	if err := j.Encode(content); err != nil {
+		logger.Log.Error("unable to write admin response", zap.Error(err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, map[string]string{
+		"error": err.Error(),
+	})
+}
@@ -4,12 +4,18 @@ import (
 	"context"
 	"fmt"
 	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
 	"kermoo/modules/planner"
+	"kermoo/modules/utils"
 	"runtime"
 	"time"
+
+	"go.uber.org/zap"
 )
 
 var _ planner.Plannable = &CpuLoader{}
+var _ metrics.Collector = &CpuLoader{}
 
 type CpuLoader struct {
 	planner.CanAssignPlan
@@ -116,10 +122,45 @@ func (cu *CpuLoader) Start(usagePercentage float64) {
 }
 
 func (cu *CpuLoader) Stop() {
+	if cu.cancel == nil {
+		return
+	}
+
 	cu.cancel()
 	time.Sleep(1 * time.Millisecond)
 }
 
+// GetTargetPercentage returns the load percentage which the CPU loader currently aims
+// for. It's zero when the plan of the loader is not running.
+func (cu *CpuLoader) GetTargetPercentage() float64 {
+	for _, plan := range cu.GetAssignedPlans() {
+		if plan.GetState() != planner.PLAN_STATE_RUNNING && plan.GetState() != planner.PLAN_STATE_PAUSED {
+			continue
+		}
+
+		if cv := plan.GetCurrentValue(); cv != nil {
+			return cv.Percentage
+		}
+	}
+
+	return 0
+}
+
+// CollectMetrics exposes the targeted CPU load along with the measured CPU usage. The usage
+// is measured system-wide, so it includes the load of the other processes of the host or
+// the container, rather than the Kermoo process alone.
+func (cu *CpuLoader) CollectMetrics(w *metrics.Writer) {
+	w.Gauge("kermoo_cpu_load_target_percentage", "Targeted CPU load in percentage.", cu.GetTargetPercentage())
+
+	usage, err := utils.GetCpuUsage(0)
+	if err != nil {
+		logger.Log.Warn("unable to measure cpu usage", zap.Error(err))
+		return
+	}
+
+	w.Gauge("kermoo_system_cpu_usage_percentage", "Measured system-wide CPU usage in percentage, including other processes.", float64(usage)*100)
+}
+
 func (cu *CpuLoader) GetContextAndCancel() (context.Context, context.CancelFunc) {
 	return cu.ctx, cu.cancel
 }
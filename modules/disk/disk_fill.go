@@ -0,0 +1,253 @@
+package disk
+
+import (
+	"fmt"
+	"io"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// chunkSize is the size of each write while filling the disk.
+const chunkSize = 1024 * 1024
+
+var _ planner.Plannable = &DiskFill{}
+var _ metrics.Collector = &DiskFill{}
+
+type DiskFill struct {
+	planner.CanAssignPlan
+
+	// PlanRefs is an optional list of plan names. It can used to avoid redundant
+	// re-declearing of plans in large-scale configurations.
+	// PlanRefs overrides Size, Interval and Duration fields are overrided in favor
+	// of the one defined in the referenced plan.
+	PlanRefs []string `json:"planRefs"`
+
+	// Directory defines where the fill file should be written. Point it to the volume you
+	// want to put under pressure, such as an ephemeral storage.
+	//
+	// Default is the temporary directory of the system.
+	Directory *string `json:"directory"`
+
+	// Size determines how much of the disk should be filled. The fill file is grown or
+	// shrunk on each cycle to match the size, so the disk usage follows the plan.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// sizes are specified, it'll act like a graph of bars and iterate over them.
+	Size *fluent.FluentSize `json:"size"`
+
+	// Interval decides how long each fill cycle should last. A value above one second is recommended
+	// but you're free  to use any interval. Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Duration defines the duration of the entire disk fill module. Leave it empty for
+	// life-long running or specify one to end the module completely after that and remove
+	// the fill file.
+	// In fact, Duration/Interval determines the number of cycle, if defined. Default is empty
+	// for unlimited activity.
+	Duration *fluent.FluentDuration `json:"duration"`
+
+	filledSize atomic.Int64
+	mu         sync.Mutex
+}
+
+func (df *DiskFill) GetName() string {
+	return "disk-filler"
+}
+
+func (df *DiskFill) GetDirectory() string {
+	if df.Directory != nil {
+		return *df.Directory
+	}
+
+	return os.TempDir()
+}
+
+// GetFilePath returns the path of the fill file. It's unique per process so that
+// replicas sharing a volume won't step on each other.
+func (df *DiskFill) GetFilePath() string {
+	return filepath.Join(df.GetDirectory(), fmt.Sprintf("kermoo-disk-fill-%d.dat", os.Getpid()))
+}
+
+// GetFilledSize returns the number of bytes which are currently written to the fill file.
+func (df *DiskFill) GetFilledSize() int64 {
+	return df.filledSize.Load()
+}
+
+func (df *DiskFill) HasInlinePlan() bool {
+	return df.MakeInlinePlan() != nil
+}
+
+func (df *DiskFill) GetDesiredPlanNames() []string {
+	return df.PlanRefs
+}
+
+func (df *DiskFill) Validate() error {
+	if len(df.PlanRefs) == 0 && !df.HasInlinePlan() {
+		return fmt.Errorf("no fill specifications or plan refs is set")
+	}
+
+	if len(df.PlanRefs) > 1 {
+		return fmt.Errorf("plan refs can not contain more than one element")
+	}
+
+	if df.HasInlinePlan() {
+		if err := df.MakeInlinePlan().Validate(); err != nil {
+			return fmt.Errorf("crafted plan validation failed: %v", err)
+		}
+	}
+
+	return validateDirectory(df.GetDirectory())
+}
+
+func (df *DiskFill) GetPlanCycleHooks() planner.CycleHooks {
+	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		if err := df.Fill(df.GetAssignedPlans()[0].GetCurrentValue().Size); err != nil {
+			logger.Log.Error("unable to fill the disk", zap.String("filename", df.GetFilePath()), zap.Error(err))
+		}
+
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	finish := planner.FinishFunc(df.Stop)
+
+	return planner.CycleHooks{
+		PreSleep: &preSleep,
+		Finish:   &finish,
+	}
+}
+
+func (df *DiskFill) MakeInlinePlan() *planner.Plan {
+	if df.Size == nil {
+		return nil
+	}
+
+	plan := planner.NewPlan(planner.Plan{
+		Size:     df.Size,
+		Interval: df.Interval,
+		Duration: df.Duration,
+	})
+
+	return &plan
+}
+
+func (df *DiskFill) MakeDefaultPlan() *planner.Plan {
+	return nil
+}
+
+// Fill grows or shrinks the fill file to the given size. The file is actually written
+// rather than being allocated sparsely so the disk usage really goes up.
+func (df *DiskFill) Fill(size int64) error {
+	df.mu.Lock()
+	defer df.mu.Unlock()
+
+	if err := os.MkdirAll(df.GetDirectory(), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(df.GetFilePath(), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	current := info.Size()
+
+	if current > size {
+		if err := file.Truncate(size); err != nil {
+			return err
+		}
+
+		df.filledSize.Store(size)
+
+		return nil
+	}
+
+	if _, err := file.Seek(current, io.SeekStart); err != nil {
+		return err
+	}
+
+	remaining := size - current
+	if remaining > chunkSize {
+		remaining = chunkSize
+	}
+
+	chunk := makeChunk(int(remaining))
+
+	for current < size {
+		n := int64(len(chunk))
+		if size-current < n {
+			n = size - current
+		}
+
+		written, err := file.Write(chunk[:n])
+		current += int64(written)
+		df.filledSize.Store(current)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return file.Sync()
+}
+
+// Stop removes the fill file and releases the disk space.
+func (df *DiskFill) Stop() {
+	df.mu.Lock()
+	defer df.mu.Unlock()
+
+	if err := os.Remove(df.GetFilePath()); err != nil && !os.IsNotExist(err) {
+		logger.Log.Error("unable to remove the disk fill file", zap.String("filename", df.GetFilePath()), zap.Error(err))
+		return
+	}
+
+	df.filledSize.Store(0)
+}
+
+// CollectMetrics exposes the number of bytes which are currently written to the disk.
+func (df *DiskFill) CollectMetrics(w *metrics.Writer) {
+	w.Gauge("kermoo_disk_filled_bytes", "Number of bytes currently written by the disk filler.", float64(df.GetFilledSize()))
+}
+
+func validateDirectory(directory string) error {
+	info, err := os.Stat(directory)
+
+	if os.IsNotExist(err) {
+		// It'll be created on demand
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to access directory %s: %v", directory, err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", directory)
+	}
+
+	return nil
+}
+
+// makeChunk makes a chunk of random bytes so that the written data can not be simply
+// compressed or deduplicated by the filesystem.
+func makeChunk(size int) []byte {
+	chunk := make([]byte, size)
+	rand.New(rand.NewSource(time.Now().UnixNano())).Read(chunk)
+
+	return chunk
+}
@@ -0,0 +1,420 @@
+package disk
+
+import (
+	"context"
+	"fmt"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	DISK_IO_MODE_READ       = "read"
+	DISK_IO_MODE_WRITE      = "write"
+	DISK_IO_MODE_READ_WRITE = "readwrite"
+)
+
+const (
+	// ioTick is the time slice in which the throughput budget is spent.
+	ioTick = 100 * time.Millisecond
+
+	// ioFileSize is the size of the file which the reads and writes are performed on. Writes
+	// wrap around it so that generating throughput won't fill the disk.
+	ioFileSize = 64 * 1024 * 1024
+
+	defaultBlockSize = 4 * 1024
+)
+
+var _ planner.Plannable = &DiskIO{}
+var _ metrics.Collector = &DiskIO{}
+
+type DiskIO struct {
+	planner.CanAssignPlan
+
+	// PlanRefs is an optional list of plan names. It can used to avoid redundant
+	// re-declearing of plans in large-scale configurations.
+	// PlanRefs overrides Throughput, Interval and Duration fields are overrided in favor
+	// of the one defined in the referenced plan.
+	// The size of the referenced plan is considered as the throughput.
+	PlanRefs []string `json:"planRefs"`
+
+	// Directory defines where the I/O should be performed. Point it to the volume you
+	// want to put under pressure.
+	//
+	// Default is the temporary directory of the system.
+	Directory *string `json:"directory"`
+
+	// Throughput determines how many bytes should be read and/or written per second.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// sizes are specified, it'll act like a graph of bars and iterate over them.
+	Throughput *fluent.FluentSize `json:"throughput"`
+
+	// Interval decides how long each throughput cycle should last. A value above one second is
+	// recommended but you're free  to use any interval. Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Duration defines the duration of the entire disk I/O module. Leave it empty for
+	// life-long running or specify one to end the module completely after that.
+	// In fact, Duration/Interval determines the number of cycle, if defined. Default is empty
+	// for unlimited activity.
+	Duration *fluent.FluentDuration `json:"duration"`
+
+	// Mode determines the kind of the I/O which can be read, write or readwrite. Reads
+	// might be served from the page cache of the operating system.
+	//
+	// Default is write.
+	Mode *string `json:"mode"`
+
+	// BlockSize determines the size of each read or write operation. It can not be used
+	// along with Iops.
+	//
+	// Default is 4Ki.
+	BlockSize *fluent.FluentSize `json:"blockSize"`
+
+	// Iops determines the number of operations per second. The size of each operation is
+	// derived from the throughput. It can not be used along with BlockSize.
+	//
+	// Default is the throughput divided by the block size.
+	Iops *int64 `json:"iops"`
+
+	// Fsync indicates each write to be flushed to the disk right away, causing an
+	// fsync storm.
+	//
+	// Default is false.
+	Fsync *bool `json:"fsync"`
+
+	throughput   atomic.Int64
+	writtenBytes atomic.Int64
+	readBytes    atomic.Int64
+	fsyncs       atomic.Int64
+	cancel       context.CancelFunc
+	stopped      chan struct{}
+	mu           sync.Mutex
+}
+
+func (dio *DiskIO) GetName() string {
+	return "disk-io"
+}
+
+func (dio *DiskIO) GetDirectory() string {
+	if dio.Directory != nil {
+		return *dio.Directory
+	}
+
+	return os.TempDir()
+}
+
+// GetFilePath returns the path of the file which the I/O is performed on.
+func (dio *DiskIO) GetFilePath() string {
+	return filepath.Join(dio.GetDirectory(), fmt.Sprintf("kermoo-disk-io-%d.dat", os.Getpid()))
+}
+
+func (dio *DiskIO) GetMode() string {
+	if dio.Mode != nil {
+		return *dio.Mode
+	}
+
+	return DISK_IO_MODE_WRITE
+}
+
+// GetThroughput returns the number of bytes per second which the module currently aims for.
+func (dio *DiskIO) GetThroughput() int64 {
+	return dio.throughput.Load()
+}
+
+// GetBlockSize returns the size of each operation for the given throughput. The size which is
+// derived from Iops is capped by the size of the I/O file.
+func (dio *DiskIO) GetBlockSize(throughput int64) int64 {
+	if dio.Iops != nil {
+		blockSize := throughput / *dio.Iops
+		if blockSize < 1 {
+			return 1
+		}
+
+		if blockSize > ioFileSize {
+			return ioFileSize
+		}
+
+		return blockSize
+	}
+
+	if dio.BlockSize != nil {
+		return dio.BlockSize.Get()
+	}
+
+	return defaultBlockSize
+}
+
+func (dio *DiskIO) HasInlinePlan() bool {
+	return dio.MakeInlinePlan() != nil
+}
+
+func (dio *DiskIO) GetDesiredPlanNames() []string {
+	return dio.PlanRefs
+}
+
+func (dio *DiskIO) Validate() error {
+	if len(dio.PlanRefs) == 0 && !dio.HasInlinePlan() {
+		return fmt.Errorf("no throughput specifications or plan refs is set")
+	}
+
+	if len(dio.PlanRefs) > 1 {
+		return fmt.Errorf("plan refs can not contain more than one element")
+	}
+
+	if dio.HasInlinePlan() {
+		if err := dio.MakeInlinePlan().Validate(); err != nil {
+			return fmt.Errorf("crafted plan validation failed: %v", err)
+		}
+	}
+
+	switch dio.GetMode() {
+	case DISK_IO_MODE_READ, DISK_IO_MODE_WRITE, DISK_IO_MODE_READ_WRITE:
+	default:
+		return fmt.Errorf("mode %s is not supported", dio.GetMode())
+	}
+
+	if dio.BlockSize != nil && dio.Iops != nil {
+		return fmt.Errorf("block size and iops can not be used together")
+	}
+
+	if dio.Iops != nil && *dio.Iops <= 0 {
+		return fmt.Errorf("iops must be greater than zero")
+	}
+
+	if dio.BlockSize != nil {
+		if err := validateBlockSize(dio.BlockSize); err != nil {
+			return err
+		}
+	}
+
+	return validateDirectory(dio.GetDirectory())
+}
+
+func (dio *DiskIO) GetPlanCycleHooks() planner.CycleHooks {
+	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		if err := dio.Start(dio.GetAssignedPlans()[0].GetCurrentValue().Size); err != nil {
+			logger.Log.Error("unable to start disk i/o", zap.String("filename", dio.GetFilePath()), zap.Error(err))
+		}
+
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	finish := planner.FinishFunc(dio.Stop)
+
+	return planner.CycleHooks{
+		PreSleep: &preSleep,
+		Finish:   &finish,
+	}
+}
+
+func (dio *DiskIO) MakeInlinePlan() *planner.Plan {
+	if dio.Throughput == nil {
+		return nil
+	}
+
+	plan := planner.NewPlan(planner.Plan{
+		Size:     dio.Throughput,
+		Interval: dio.Interval,
+		Duration: dio.Duration,
+	})
+
+	return &plan
+}
+
+func (dio *DiskIO) MakeDefaultPlan() *planner.Plan {
+	return nil
+}
+
+// Start sets the throughput in bytes per second and starts the I/O if it's not already
+// running.
+func (dio *DiskIO) Start(throughput int64) error {
+	dio.mu.Lock()
+	defer dio.mu.Unlock()
+
+	dio.throughput.Store(throughput)
+
+	if dio.cancel != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(dio.GetDirectory(), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(dio.GetFilePath(), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+
+	if dio.GetMode() != DISK_IO_MODE_WRITE {
+		if err := prepareReadableFile(file); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dio.cancel = cancel
+	dio.stopped = make(chan struct{})
+
+	go dio.run(ctx, file, dio.stopped)
+
+	return nil
+}
+
+// Stop ends the I/O and removes its file.
+func (dio *DiskIO) Stop() {
+	dio.mu.Lock()
+	defer dio.mu.Unlock()
+
+	dio.throughput.Store(0)
+
+	if dio.cancel == nil {
+		return
+	}
+
+	dio.cancel()
+	<-dio.stopped
+	dio.cancel = nil
+
+	if err := os.Remove(dio.GetFilePath()); err != nil && !os.IsNotExist(err) {
+		logger.Log.Error("unable to remove the disk i/o file", zap.String("filename", dio.GetFilePath()), zap.Error(err))
+	}
+}
+
+func (dio *DiskIO) run(ctx context.Context, file *os.File, stopped chan struct{}) {
+	defer close(stopped)
+	defer file.Close()
+
+	ticker := time.NewTicker(ioTick)
+	defer ticker.Stop()
+
+	offset := int64(0)
+	credit := int64(0)
+	shouldWrite := dio.GetMode() != DISK_IO_MODE_READ
+	buffer := []byte{}
+
+	for {
+		throughput := dio.GetThroughput()
+		blockSize := dio.GetBlockSize(throughput)
+
+		if int64(len(buffer)) < blockSize {
+			buffer = makeChunk(int(blockSize))
+		}
+
+		block := buffer[:blockSize]
+
+		// Unspent credit is carried over so that throughputs lower than a block per tick
+		// are respected too, but never more than a second worth of it.
+		credit += throughput * int64(ioTick) / int64(time.Second)
+		if credit > throughput+blockSize {
+			credit = throughput + blockSize
+		}
+
+		for ; credit >= blockSize; credit -= blockSize {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if offset+blockSize > ioFileSize {
+				offset = 0
+			}
+
+			if err := dio.operate(file, block, offset, shouldWrite); err != nil {
+				logger.Log.Error("disk i/o operation failed", zap.String("filename", file.Name()), zap.Error(err))
+				break
+			}
+
+			offset += blockSize
+
+			if dio.GetMode() == DISK_IO_MODE_READ_WRITE {
+				shouldWrite = !shouldWrite
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (dio *DiskIO) operate(file *os.File, block []byte, offset int64, shouldWrite bool) error {
+	if !shouldWrite {
+		n, err := file.ReadAt(block, offset)
+		dio.readBytes.Add(int64(n))
+
+		return err
+	}
+
+	n, err := file.WriteAt(block, offset)
+	dio.writtenBytes.Add(int64(n))
+
+	if err != nil {
+		return err
+	}
+
+	if dio.Fsync != nil && *dio.Fsync {
+		dio.fsyncs.Add(1)
+		return file.Sync()
+	}
+
+	return nil
+}
+
+// CollectMetrics exposes the targeted throughput along with the number of performed I/O.
+func (dio *DiskIO) CollectMetrics(w *metrics.Writer) {
+	w.Gauge("kermoo_disk_io_target_bytes_per_second", "Targeted disk I/O throughput in bytes per second.", float64(dio.GetThroughput()))
+	w.Counter("kermoo_disk_io_written_bytes_total", "Number of bytes written by the disk I/O module.", float64(dio.writtenBytes.Load()))
+	w.Counter("kermoo_disk_io_read_bytes_total", "Number of bytes read by the disk I/O module.", float64(dio.readBytes.Load()))
+	w.Counter("kermoo_disk_io_fsyncs_total", "Number of fsync calls made by the disk I/O module.", float64(dio.fsyncs.Load()))
+}
+
+func validateBlockSize(blockSize *fluent.FluentSize) error {
+	pv := blockSize.GetParsedValue()
+	if pv == nil {
+		return fmt.Errorf("block size is invalid")
+	}
+
+	values := pv.GetValues()
+	if min, max, err := pv.GetRange(); err == nil {
+		values = []int64{min, max}
+	}
+
+	for _, value := range values {
+		if value <= 0 || value > ioFileSize {
+			return fmt.Errorf("block size must be between 1 and %d bytes", ioFileSize)
+		}
+	}
+
+	return nil
+}
+
+// prepareReadableFile writes the whole I/O file once so that there's something to be read.
+func prepareReadableFile(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	chunk := makeChunk(chunkSize)
+
+	for offset := info.Size(); offset < ioFileSize; offset += chunkSize {
+		if _, err := file.WriteAt(chunk, offset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
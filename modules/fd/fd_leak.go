@@ -0,0 +1,269 @@
+package fd
+
+import (
+	"fmt"
+	"io"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+const (
+	FD_KIND_FILES   = "files"
+	FD_KIND_PIPES   = "pipes"
+	FD_KIND_SOCKETS = "sockets"
+)
+
+var _ planner.Plannable = &FdLeak{}
+var _ metrics.Collector = &FdLeak{}
+
+var leakErrorsCounter = metrics.NewCounterVec(
+	"kermoo_fd_leak_errors_total",
+	"Number of failures to open a file descriptor, such as hitting the limit of open files.",
+	"kind",
+)
+
+type FdLeak struct {
+	planner.CanAssignPlan
+
+	// PlanRefs is an optional list of plan names. It can used to avoid redundant
+	// re-declearing of plans in large-scale configurations.
+	// PlanRefs overrides Count, Interval and Duration fields are overrided in favor
+	// of the one defined in the referenced plan.
+	// The size of the referenced plan is considered as the count.
+	PlanRefs []string `json:"planRefs"`
+
+	// Kind determines what kind of descriptors should be leaked which can be files, pipes
+	// or sockets. Each pipe takes two descriptors (read and write ends) and each socket
+	// takes two descriptors too (an idle TCP connection to a local listener along with its
+	// accepted end).
+	//
+	// Default is files.
+	Kind *string `json:"kind"`
+
+	// Count determines the number of file descriptors to leak. This number of descriptors
+	// will be used in addition to the ones used by the Kermoo application itself.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// counts are specified, it'll act like a graph of bars and iterate over them.
+	Count *fluent.FluentSize `json:"count"`
+
+	// Interval decides how long each leak cycle should last. A value above one second is recommended
+	// but you're free  to use any interval. Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Duration defines the duration of the entire file descriptor leak module. Leave it empty for
+	// life-long running or specify one to end the module completely after that.
+	// In fact, Duration/Interval determines the number of cycle, if defined. Default is empty
+	// for unlimited activity.
+	Duration *fluent.FluentDuration `json:"duration"`
+
+	leaked      []io.Closer
+	leakedCount atomic.Int64
+	listener    net.Listener
+	mu          sync.Mutex
+}
+
+func (fl *FdLeak) GetName() string {
+	return "fd-leaker"
+}
+
+func (fl *FdLeak) GetKind() string {
+	if fl.Kind != nil {
+		return *fl.Kind
+	}
+
+	return FD_KIND_FILES
+}
+
+// GetLeakedCount returns the number of file descriptors which are currently leaked.
+func (fl *FdLeak) GetLeakedCount() int64 {
+	return fl.leakedCount.Load()
+}
+
+func (fl *FdLeak) HasInlinePlan() bool {
+	return fl.MakeInlinePlan() != nil
+}
+
+func (fl *FdLeak) GetDesiredPlanNames() []string {
+	return fl.PlanRefs
+}
+
+func (fl *FdLeak) Validate() error {
+	if len(fl.PlanRefs) == 0 && !fl.HasInlinePlan() {
+		return fmt.Errorf("no leak specifications or plan refs is set")
+	}
+
+	if len(fl.PlanRefs) > 1 {
+		return fmt.Errorf("plan refs can not contain more than one element")
+	}
+
+	switch fl.GetKind() {
+	case FD_KIND_FILES, FD_KIND_PIPES, FD_KIND_SOCKETS:
+	default:
+		return fmt.Errorf("kind %s is not supported", fl.GetKind())
+	}
+
+	if fl.HasInlinePlan() {
+		if err := fl.MakeInlinePlan().Validate(); err != nil {
+			return fmt.Errorf("crafted plan validation failed: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (fl *FdLeak) GetPlanCycleHooks() planner.CycleHooks {
+	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		fl.StartLeaking(
+			fl.GetAssignedPlans()[0].GetCurrentValue().Size,
+		)
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	postSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		fl.StopLeaking()
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	return planner.CycleHooks{
+		PreSleep:  &preSleep,
+		PostSleep: &postSleep,
+	}
+}
+
+func (fl *FdLeak) MakeInlinePlan() *planner.Plan {
+	if fl.Count == nil {
+		return nil
+	}
+
+	plan := planner.NewPlan(planner.Plan{
+		Size:     fl.Count,
+		Interval: fl.Interval,
+		Duration: fl.Duration,
+	})
+
+	return &plan
+}
+
+func (fl *FdLeak) MakeDefaultPlan() *planner.Plan {
+	return nil
+}
+
+// StartLeaking opens file descriptors until the given count is reached. It stops early
+// when descriptors can not be opened anymore, e.g. by hitting the limit of open files.
+func (fl *FdLeak) StartLeaking(count int64) {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	for fl.leakedCount.Load() < count {
+		closers, err := fl.open()
+
+		if err != nil {
+			leakErrorsCounter.Inc(fl.GetKind())
+			logger.Log.Warn(
+				"unable to leak more file descriptors",
+				zap.String("kind", fl.GetKind()),
+				zap.Int64("leaked", fl.leakedCount.Load()),
+				zap.Int64("desired", count),
+				zap.Error(err),
+			)
+			return
+		}
+
+		fl.leaked = append(fl.leaked, closers...)
+		fl.leakedCount.Add(int64(len(closers)))
+	}
+}
+
+// StopLeaking closes all of the leaked file descriptors.
+func (fl *FdLeak) StopLeaking() {
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	for _, closer := range fl.leaked {
+		closer.Close()
+	}
+
+	fl.leaked = nil
+	fl.leakedCount.Store(0)
+}
+
+// Stop closes all of the leaked file descriptors along with the local listener used to
+// leak sockets.
+func (fl *FdLeak) Stop() {
+	fl.StopLeaking()
+
+	fl.mu.Lock()
+	defer fl.mu.Unlock()
+
+	if fl.listener != nil {
+		fl.listener.Close()
+		fl.listener = nil
+	}
+}
+
+// CollectMetrics exposes the number of file descriptors which are currently leaked.
+func (fl *FdLeak) CollectMetrics(w *metrics.Writer) {
+	w.Gauge(
+		"kermoo_fd_leaked",
+		"Number of file descriptors currently leaked by the file descriptor leaker.",
+		float64(fl.GetLeakedCount()),
+		metrics.Label{Name: "kind", Value: fl.GetKind()},
+	)
+}
+
+// open opens the file descriptors of a single leak unit depending on the kind.
+func (fl *FdLeak) open() ([]io.Closer, error) {
+	switch fl.GetKind() {
+	case FD_KIND_PIPES:
+		r, w, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+
+		return []io.Closer{r, w}, nil
+	case FD_KIND_SOCKETS:
+		return fl.openSocket()
+	default:
+		file, err := os.Open(os.DevNull)
+		if err != nil {
+			return nil, err
+		}
+
+		return []io.Closer{file}, nil
+	}
+}
+
+func (fl *FdLeak) openSocket() ([]io.Closer, error) {
+	if fl.listener == nil {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+
+		fl.listener = listener
+	}
+
+	conn, err := net.Dial("tcp", fl.listener.Addr().String())
+	if err != nil {
+		return nil, err
+	}
+
+	// Hold the accepted end too, otherwise it'll be sitting in the backlog. The connection
+	// is already established, so accepting it doesn't block.
+	accepted, err := fl.listener.Accept()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return []io.Closer{conn, accepted}, nil
+}
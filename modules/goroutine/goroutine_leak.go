@@ -0,0 +1,253 @@
+package goroutine
+
+import (
+	"fmt"
+	"kermoo/modules/fluent"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"kermoo/modules/utils"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultThreadsLimit keeps the locked threads away from the limit of the Go runtime
+// (10000 by default) which crashes the whole process once exceeded.
+const defaultThreadsLimit = 5000
+
+// maxThreadsLimit is the highest limit of the locked threads, leaving room for the threads
+// of the Go runtime and the rest of Kermoo below the limit of the Go runtime.
+const maxThreadsLimit = 9000
+
+var (
+	leakedGoroutines atomic.Int64
+	lockedThreads    atomic.Int64
+)
+
+// GetLeakedGoroutines returns the number of goroutines which are currently leaked,
+// including the ones locked to an OS thread.
+func GetLeakedGoroutines() int64 {
+	return leakedGoroutines.Load()
+}
+
+// GetLockedThreads returns the number of OS threads which are currently held by the
+// leaked goroutines.
+func GetLockedThreads() int64 {
+	return lockedThreads.Load()
+}
+
+var _ planner.Plannable = &GoroutineLeak{}
+var _ metrics.Collector = &GoroutineLeak{}
+
+type GoroutineLeak struct {
+	planner.CanAssignPlan
+
+	// PlanRefs is an optional list of plan names. It can used to avoid redundant
+	// re-declearing of plans in large-scale configurations.
+	// PlanRefs overrides Rate, Interval and Duration fields are overrided in favor
+	// of the one defined in the referenced plan.
+	// The size of the referenced plan is considered as the rate.
+	PlanRefs []string `json:"planRefs"`
+
+	// Rate determines the number of goroutines to be spawned and left blocked on each
+	// cycle. Leaked goroutines are piled up until the module ends.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// rates are specified, it'll act like a graph of bars and iterate over them.
+	Rate *fluent.FluentSize `json:"rate"`
+
+	// Interval decides how long each leak cycle should last. A value above one second is recommended
+	// but you're free  to use any interval. Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Duration defines the duration of the entire goroutine leak module. Leave it empty for
+	// life-long running or specify one to end the module completely after that and release
+	// all of the leaked goroutines.
+	// In fact, Duration/Interval determines the number of cycle, if defined. Default is empty
+	// for unlimited activity.
+	Duration *fluent.FluentDuration `json:"duration"`
+
+	// LockOSThread indicates each leaked goroutine to be locked to its own OS thread, so
+	// that the thread count of the process grows too.
+	//
+	// Default is false.
+	LockOSThread *bool `json:"lockOsThread"`
+
+	// Limit determines the maximum number of goroutines which can be leaked in total.
+	//
+	// Default is unlimited for goroutines and 5000 for the ones locked to OS threads, since
+	// the Go runtime crashes the process on exceeding 10000 threads. For the same reason, it
+	// can not be more than 9000 for the ones locked to OS threads.
+	Limit *int64 `json:"limit"`
+
+	leaked  int64
+	release chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+}
+
+func (gl *GoroutineLeak) GetName() string {
+	return "goroutine-leaker"
+}
+
+func (gl *GoroutineLeak) ShouldLockOSThread() bool {
+	return gl.LockOSThread != nil && *gl.LockOSThread
+}
+
+// GetLimit returns the maximum number of goroutines which can be leaked, or zero for
+// unlimited.
+func (gl *GoroutineLeak) GetLimit() int64 {
+	if gl.Limit != nil {
+		return *gl.Limit
+	}
+
+	if gl.ShouldLockOSThread() {
+		return defaultThreadsLimit
+	}
+
+	return 0
+}
+
+func (gl *GoroutineLeak) HasInlinePlan() bool {
+	return gl.MakeInlinePlan() != nil
+}
+
+func (gl *GoroutineLeak) GetDesiredPlanNames() []string {
+	return gl.PlanRefs
+}
+
+func (gl *GoroutineLeak) Validate() error {
+	if len(gl.PlanRefs) == 0 && !gl.HasInlinePlan() {
+		return fmt.Errorf("no leak specifications or plan refs is set")
+	}
+
+	if len(gl.PlanRefs) > 1 {
+		return fmt.Errorf("plan refs can not contain more than one element")
+	}
+
+	if gl.Limit != nil && *gl.Limit <= 0 {
+		return fmt.Errorf("limit must be greater than zero")
+	}
+
+	if gl.Limit != nil && gl.ShouldLockOSThread() && *gl.Limit > maxThreadsLimit {
+		return fmt.Errorf("limit of goroutines locked to os threads can not be more than %d", maxThreadsLimit)
+	}
+
+	if gl.HasInlinePlan() {
+		if err := gl.MakeInlinePlan().Validate(); err != nil {
+			return fmt.Errorf("crafted plan validation failed: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (gl *GoroutineLeak) GetPlanCycleHooks() planner.CycleHooks {
+	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		gl.Leak(
+			gl.GetAssignedPlans()[0].GetCurrentValue().Size,
+		)
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	finish := planner.FinishFunc(gl.Stop)
+
+	return planner.CycleHooks{
+		PreSleep: &preSleep,
+		Finish:   &finish,
+	}
+}
+
+func (gl *GoroutineLeak) MakeInlinePlan() *planner.Plan {
+	if gl.Rate == nil {
+		return nil
+	}
+
+	plan := planner.NewPlan(planner.Plan{
+		Size:     gl.Rate,
+		Interval: gl.Interval,
+		Duration: gl.Duration,
+	})
+
+	return &plan
+}
+
+func (gl *GoroutineLeak) MakeDefaultPlan() *planner.Plan {
+	return nil
+}
+
+// GetLeakedCount returns the number of goroutines which are currently leaked by the module.
+func (gl *GoroutineLeak) GetLeakedCount() int64 {
+	gl.mu.Lock()
+	defer gl.mu.Unlock()
+
+	return gl.leaked
+}
+
+// Leak spawns the given number of blocked goroutines in addition to the already leaked
+// ones, with respect to the limit.
+func (gl *GoroutineLeak) Leak(count int64) {
+	gl.mu.Lock()
+	defer gl.mu.Unlock()
+
+	if gl.release == nil {
+		gl.release = make(chan struct{})
+	}
+
+	if limit := gl.GetLimit(); limit > 0 && gl.leaked+count > limit {
+		count = limit - gl.leaked
+	}
+
+	lockOSThread := gl.ShouldLockOSThread()
+
+	for i := int64(0); i < count; i++ {
+		gl.wg.Add(1)
+		gl.leaked++
+		leakedGoroutines.Add(1)
+
+		if lockOSThread {
+			lockedThreads.Add(1)
+		}
+
+		go block(gl.release, lockOSThread, &gl.wg)
+	}
+}
+
+// Stop releases all of the leaked goroutines and waits for them to exit. Threads locked by
+// them are terminated as well.
+func (gl *GoroutineLeak) Stop() {
+	gl.mu.Lock()
+	defer gl.mu.Unlock()
+
+	if gl.release == nil {
+		return
+	}
+
+	close(gl.release)
+	gl.wg.Wait()
+
+	gl.release = nil
+	gl.leaked = 0
+}
+
+// CollectMetrics exposes the number of leaked goroutines along with the total number of
+// goroutines and threads of the process.
+func (gl *GoroutineLeak) CollectMetrics(w *metrics.Writer) {
+	w.Gauge("kermoo_goroutines_leaked", "Number of goroutines currently leaked by the goroutine leaker.", float64(gl.GetLeakedCount()))
+	w.Gauge("kermoo_goroutines", "Number of goroutines of the process.", float64(runtime.NumGoroutine()))
+	w.Gauge("kermoo_threads", "Number of OS threads of the process.", float64(utils.GetThreadCount()))
+}
+
+func block(release chan struct{}, lockOSThread bool, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	if lockOSThread {
+		// The thread is terminated once the goroutine exits without unlocking it
+		runtime.LockOSThread()
+		defer lockedThreads.Add(-1)
+	}
+
+	defer leakedGoroutines.Add(-1)
+
+	<-release
+}
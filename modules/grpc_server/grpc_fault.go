@@ -0,0 +1,83 @@
+package grpc_server
+
+import (
+	"fmt"
+	"kermoo/modules/fluent"
+	"math/rand"
+)
+
+const (
+	GRPC_FAULT_UNAVAILABLE        = "unavailable"
+	GRPC_FAULT_DEADLINE_EXCEEDED  = "deadline_exceeded"
+	GRPC_FAULT_RESOURCE_EXHAUSTED = "resource_exhausted"
+	GRPC_FAULT_NOT_SERVING        = "not_serving"
+)
+
+type GrpcServerFault struct {
+	// PlanRefs is an optional list of plan names. It can used to avoid redundant
+	// re-declearing of plans in large-scale configurations.
+	// PlanRefs overrides Percentage, Interval and Duration fields are overrided in favor
+	// of the one defined in the referenced plan.
+	PlanRefs []string `json:"planRefs"`
+
+	// Percentage determines the chance of failing. 0 means no not failure at all and 100
+	// means always failing. By failing, we mean the server will misbehave in one of the
+	// ways defined in Types.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// percentages are specified, it'll act like a graph of bars and iterate over them.
+	Percentage fluent.FluentFloat `json:"percentage"`
+
+	// Interval decides how long each desicion to stay failing or serving should last.
+	// A value above one second is recommended but you're free  to use any interval.
+	// Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Duration defines the duration of the entire server. Leave it empty for
+	// life-long running or specify one to end the module completely after that and last decision
+	// will be happening for ever.
+	// In fact, Duration/Interval determines the number of cycle, if defined. Default is empty
+	// for unlimited activity.
+	Duration *fluent.FluentDuration `json:"duration"`
+
+	// Types defines how the server can misbehave when it's in failing state. One of them is
+	// picked randomly for each failing cycle:
+	//
+	// - unavailable: fails all of the calls, health checks included, with UNAVAILABLE.
+	// - deadline_exceeded: holds the calls until their deadline is exceeded and fails them
+	//   with DEADLINE_EXCEEDED. Calls with no deadline are failed right away.
+	// - resource_exhausted: fails all of the calls, health checks included, with
+	//   RESOURCE_EXHAUSTED.
+	// - not_serving: reports NOT_SERVING on the health checks while the rest of the calls
+	//   are served.
+	//
+	// Default is all of them.
+	Types []string `json:"types"`
+}
+
+func (gf *GrpcServerFault) GetTypes() []string {
+	if len(gf.Types) > 0 {
+		return gf.Types
+	}
+
+	return []string{GRPC_FAULT_UNAVAILABLE, GRPC_FAULT_DEADLINE_EXCEEDED, GRPC_FAULT_RESOURCE_EXHAUSTED, GRPC_FAULT_NOT_SERVING}
+}
+
+// PickType picks one of the fault types randomly.
+func (gf *GrpcServerFault) PickType() string {
+	types := gf.GetTypes()
+
+	return types[rand.Intn(len(types))]
+}
+
+func (gf *GrpcServerFault) Validate() error {
+	for _, t := range gf.Types {
+		switch t {
+		case GRPC_FAULT_UNAVAILABLE, GRPC_FAULT_DEADLINE_EXCEEDED, GRPC_FAULT_RESOURCE_EXHAUSTED, GRPC_FAULT_NOT_SERVING:
+		default:
+			return fmt.Errorf("fault type %s is not supported", t)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,365 @@
+package grpc_server
+
+import (
+	"context"
+	"fmt"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"kermoo/modules/process"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gosimple/slug"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// ECHO_SERVICE is the name of the echo service which is served along with the health service.
+const ECHO_SERVICE = "kermoo.Echo"
+
+var _ planner.Plannable = &GrpcServer{}
+var _ metrics.Collector = &GrpcServer{}
+
+var faultsCounter = metrics.NewCounterVec(
+	"kermoo_grpcserver_faults_total",
+	"Number of calls which are faulted by the grpc servers.",
+	"grpcserver", "fault",
+)
+
+// GrpcServer serves the standard grpc.health.v1.Health service along with a kermoo.Echo
+// service, whose Echo method responds with the very same message it receives - whatever
+// its type is.
+type GrpcServer struct {
+	planner.CanAssignPlan
+
+	// Interface defines the network interface which the server should listen on. Default
+	// is 0.0.0.0 but you're free to define another one like 127.0.0.1.
+	Interface *string `json:"interface"`
+
+	// Port defines the port which the server should listen on. It's required.
+	Port *int32 `json:"port"`
+
+	// Fault specifies how the server should fail. Default is no failure.
+	Fault *GrpcServerFault `json:"fault"`
+
+	server      *grpc.Server
+	health      *health.Server
+	fault       string
+	stopped     chan struct{}
+	isListening atomic.Bool
+	mu          sync.Mutex
+}
+
+func (gs *GrpcServer) GetName() string {
+	return slug.Make(fmt.Sprintf("grpcserver-%s-%d", gs.GetInterface(), gs.GetPort()))
+}
+
+func (gs *GrpcServer) GetPort() int32 {
+	if gs.Port != nil {
+		return *gs.Port
+	}
+
+	return 0
+}
+
+func (gs *GrpcServer) GetInterface() string {
+	if gs.Interface != nil {
+		return *gs.Interface
+	}
+
+	return "0.0.0.0"
+}
+
+func (gs *GrpcServer) GetAddress() string {
+	return fmt.Sprintf("%s:%d", gs.GetInterface(), gs.GetPort())
+}
+
+func (gs *GrpcServer) Validate() error {
+	if gs.GetPort() <= 0 || gs.GetPort() > 65535 {
+		return fmt.Errorf("port is required and must be between 1 and 65535")
+	}
+
+	if gs.Fault != nil {
+		if err := gs.Fault.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsListening reports whether the server is up and accepting connections.
+func (gs *GrpcServer) IsListening() bool {
+	return gs.isListening.Load()
+}
+
+// GetFault returns the fault which is applied on the calls, or an empty string when the
+// server is healthy.
+func (gs *GrpcServer) GetFault() string {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	return gs.fault
+}
+
+// SetFault sets the fault to be applied on the upcoming calls and starts listening, if
+// it's not listening already.
+func (gs *GrpcServer) SetFault(fault string) error {
+	gs.mu.Lock()
+	gs.fault = fault
+	gs.applyHealthStatus()
+	gs.mu.Unlock()
+
+	return gs.Listen()
+}
+
+// applyHealthStatus reports the health status with respect to the current fault. It must
+// be called while the mutex is held.
+func (gs *GrpcServer) applyHealthStatus() {
+	if gs.health == nil {
+		return
+	}
+
+	servingStatus := healthpb.HealthCheckResponse_SERVING
+	if gs.fault == GRPC_FAULT_NOT_SERVING {
+		servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	gs.health.SetServingStatus("", servingStatus)
+	gs.health.SetServingStatus(ECHO_SERVICE, servingStatus)
+}
+
+// Listen starts listening and serving the calls on the background.
+func (gs *GrpcServer) Listen() error {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	if gs.server != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", gs.GetAddress())
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Info("listening grpc server...", zap.String("grpcserver", gs.GetName()))
+
+	gs.server = grpc.NewServer(
+		grpc.UnaryInterceptor(gs.interceptUnary),
+		grpc.StreamInterceptor(gs.interceptStream),
+	)
+	gs.health = health.NewServer()
+	gs.applyHealthStatus()
+
+	healthpb.RegisterHealthServer(gs.server, gs.health)
+	gs.server.RegisterService(&echoServiceDesc, gs)
+
+	gs.stopped = make(chan struct{})
+	gs.isListening.Store(true)
+
+	go func(server *grpc.Server, stopped chan struct{}) {
+		defer close(stopped)
+
+		if err := server.Serve(listener); err != nil {
+			logger.Log.Error("failed on serving grpc server", zap.String("grpcserver", gs.GetName()), zap.Error(err))
+		}
+	}(gs.server, gs.stopped)
+
+	return nil
+}
+
+// Stop stops the server right away, closing all of the connections.
+func (gs *GrpcServer) Stop() {
+	gs.Drain(0)
+}
+
+// Drain stops accepting new connections and waits for the in-flight calls to be served
+// within the given grace period. The remaining connections are closed afterwards.
+func (gs *GrpcServer) Drain(gracePeriod time.Duration) {
+	gs.mu.Lock()
+	server := gs.server
+	stopped := gs.stopped
+	gs.server = nil
+	gs.health = nil
+	gs.mu.Unlock()
+
+	if server == nil {
+		return
+	}
+
+	logger.Log.Info("shutting down grpc server...", zap.String("grpcserver", gs.GetName()), zap.Duration("grace_period", gracePeriod))
+
+	drained := make(chan struct{})
+
+	go func() {
+		server.GracefulStop()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(gracePeriod):
+		server.Stop()
+	}
+
+	<-stopped
+	gs.isListening.Store(false)
+}
+
+// intercept applies the current fault on the call. It returns a nil error when the call
+// should be served.
+func (gs *GrpcServer) intercept(ctx context.Context) error {
+	process.WaitWhileHung()
+
+	fault := gs.GetFault()
+
+	switch fault {
+	case GRPC_FAULT_UNAVAILABLE:
+		faultsCounter.Inc(gs.GetName(), fault)
+		return status.Error(codes.Unavailable, "server is unavailable on purpose")
+	case GRPC_FAULT_RESOURCE_EXHAUSTED:
+		faultsCounter.Inc(gs.GetName(), fault)
+		return status.Error(codes.ResourceExhausted, "resources are exhausted on purpose")
+	case GRPC_FAULT_DEADLINE_EXCEEDED:
+		faultsCounter.Inc(gs.GetName(), fault)
+
+		if _, ok := ctx.Deadline(); ok {
+			<-ctx.Done()
+		}
+
+		return status.Error(codes.DeadlineExceeded, "deadline is exceeded on purpose")
+	}
+
+	return nil
+}
+
+func (gs *GrpcServer) interceptUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := gs.intercept(ctx); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (gs *GrpcServer) interceptStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := gs.intercept(ss.Context()); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+// echoServiceDesc describes the echo service. Messages are decoded into an empty message,
+// which keeps all of their fields as unknown ones, so any message is echoed back as is.
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: ECHO_SERVICE,
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Echo",
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := &emptypb.Empty{}
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+
+				echo := func(ctx context.Context, req any) (any, error) {
+					return req, nil
+				}
+
+				if interceptor == nil {
+					return echo(ctx, in)
+				}
+
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ECHO_SERVICE + "/Echo"}, echo)
+			},
+		},
+	},
+}
+
+func (gs *GrpcServer) HasInlinePlan() bool {
+	return gs.MakeInlinePlan() != nil
+}
+
+func (gs *GrpcServer) MakeInlinePlan() *planner.Plan {
+	if gs.Fault == nil {
+		return nil
+	}
+
+	plan := planner.NewPlan(planner.Plan{
+		Percentage: &gs.Fault.Percentage,
+		Interval:   gs.Fault.Interval,
+		Duration:   gs.Fault.Duration,
+	})
+
+	return &plan
+}
+
+// Create a lifetime-long plan to serve grpc server
+func (gs *GrpcServer) MakeDefaultPlan() *planner.Plan {
+	plan := planner.NewPlan(planner.Plan{})
+
+	// Value of 0.0 indicates that the server will never fail.
+	plan.Percentage = fluent.NewMustFluentFloat("0.0")
+
+	return &plan
+}
+
+func (gs *GrpcServer) GetDesiredPlanNames() []string {
+	if gs.Fault == nil {
+		return nil
+	}
+
+	return gs.Fault.PlanRefs
+}
+
+func (gs *GrpcServer) getPlanPercentageState() bool {
+	for _, plan := range gs.GetAssignedPlans() {
+		if !*plan.GetCurrentValue().ComputedPercentageChance {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (gs *GrpcServer) GetPlanCycleHooks() planner.CycleHooks {
+	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		fault := ""
+
+		if !gs.getPlanPercentageState() && gs.Fault != nil {
+			fault = gs.Fault.PickType()
+		}
+
+		if err := gs.SetFault(fault); err != nil {
+			logger.Log.Error("error while listening to grpc server", zap.String("grpcserver", gs.GetName()), zap.Error(err))
+		}
+
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	return planner.CycleHooks{
+		PreSleep: &preSleep,
+	}
+}
+
+// CollectMetrics exposes the up/down state and the current fault of the server.
+func (gs *GrpcServer) CollectMetrics(w *metrics.Writer) {
+	labels := []metrics.Label{
+		{Name: "grpcserver", Value: gs.GetName()},
+		{Name: "address", Value: gs.GetAddress()},
+	}
+
+	w.Gauge("kermoo_grpcserver_up", "Whether the grpc server is listening (1) or not (0).", metrics.BoolToFloat(gs.IsListening()), labels...)
+	w.Gauge("kermoo_grpcserver_faulted", "Whether the grpc server is faulted (1) or not (0).", metrics.BoolToFloat(gs.GetFault() != ""), labels...)
+}
@@ -3,10 +3,13 @@ package memory
 import (
 	"fmt"
 	"kermoo/modules/fluent"
+	"kermoo/modules/metrics"
 	"kermoo/modules/planner"
+	"sync/atomic"
 )
 
 var _ planner.Plannable = &MemoryLeak{}
+var _ metrics.Collector = &MemoryLeak{}
 
 type MemoryLeak struct {
 	planner.CanAssignPlan
@@ -37,12 +40,23 @@ type MemoryLeak struct {
 	Duration *fluent.FluentDuration `json:"duration"`
 
 	leakedData []byte
+	leakedSize atomic.Int64
 }
 
 func (mu *MemoryLeak) GetLeakedData() []byte {
 	return mu.leakedData
 }
 
+// GetLeakedSize returns the number of bytes which are currently leaked.
+func (mu *MemoryLeak) GetLeakedSize() int64 {
+	return mu.leakedSize.Load()
+}
+
+// CollectMetrics exposes the number of bytes which are currently leaked.
+func (mu *MemoryLeak) CollectMetrics(w *metrics.Writer) {
+	w.Gauge("kermoo_memory_leaked_bytes", "Number of bytes currently leaked by the memory leaker.", float64(mu.GetLeakedSize()))
+}
+
 func (mu *MemoryLeak) GetName() string {
 	return "memory-leaker"
 }
@@ -112,8 +126,10 @@ func (mu *MemoryLeak) MakeDefaultPlan() *planner.Plan {
 
 func (mu *MemoryLeak) StartLeaking(size int64) {
 	mu.leakedData = make([]byte, size)
+	mu.leakedSize.Store(size)
 }
 
 func (mu *MemoryLeak) StopLeaking() {
 	mu.leakedData = make([]byte, 0)
+	mu.leakedSize.Store(0)
 }
@@ -0,0 +1,234 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	TYPE_GAUGE   = "gauge"
+	TYPE_COUNTER = "counter"
+)
+
+// Collector is implemented by the modules which expose metrics about their own state.
+type Collector interface {
+	CollectMetrics(w *Writer)
+}
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+type sample struct {
+	labels []Label
+	value  float64
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    string
+	samples []sample
+}
+
+// Writer gathers metric samples and writes them in the Prometheus text exposition format.
+type Writer struct {
+	families []*family
+}
+
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// Gauge adds a gauge sample to the writer.
+func (w *Writer) Gauge(name string, help string, value float64, labels ...Label) {
+	w.add(name, help, TYPE_GAUGE, value, labels)
+}
+
+// Counter adds a counter sample to the writer.
+func (w *Writer) Counter(name string, help string, value float64, labels ...Label) {
+	w.add(name, help, TYPE_COUNTER, value, labels)
+}
+
+func (w *Writer) add(name string, help string, kind string, value float64, labels []Label) {
+	var f *family
+
+	for _, existing := range w.families {
+		if existing.name == name {
+			f = existing
+		}
+	}
+
+	if f == nil {
+		f = &family{name: name, help: help, kind: kind}
+		w.families = append(w.families, f)
+	}
+
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+// WriteTo writes the gathered samples in the text exposition format.
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	var sb strings.Builder
+
+	for _, f := range w.families {
+		fmt.Fprintf(&sb, "# HELP %s %s\n", f.name, escape(f.help, false))
+		fmt.Fprintf(&sb, "# TYPE %s %s\n", f.name, f.kind)
+
+		for _, s := range f.samples {
+			sb.WriteString(f.name)
+
+			if len(s.labels) > 0 {
+				pairs := []string{}
+				for _, l := range s.labels {
+					pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", l.Name, escape(l.Value, true)))
+				}
+				sb.WriteString("{" + strings.Join(pairs, ",") + "}")
+			}
+
+			sb.WriteString(" " + formatValue(s.value) + "\n")
+		}
+	}
+
+	n, err := io.WriteString(out, sb.String())
+
+	return int64(n), err
+}
+
+// Gather collects the metrics of the given collectors along with the registered counters.
+func Gather(collectors []Collector) *Writer {
+	w := NewWriter()
+
+	for _, c := range collectors {
+		c.CollectMetrics(w)
+	}
+
+	registryMutex.Lock()
+	counters := append([]*CounterVec{}, registry...)
+	registryMutex.Unlock()
+
+	for _, c := range counters {
+		c.CollectMetrics(w)
+	}
+
+	return w
+}
+
+var (
+	registry      []*CounterVec
+	registryMutex sync.Mutex
+)
+
+// CounterVec is a registered counter which is partitioned by the values of its labels.
+// Its values survive the reloads of the modules which increase it.
+type CounterVec struct {
+	name       string
+	help       string
+	labelNames []string
+	values     map[string]float64
+	labels     map[string][]Label
+	mu         sync.Mutex
+}
+
+// NewCounterVec makes and registers a counter so that it'll be exposed on every gathering.
+func NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		values:     map[string]float64{},
+		labels:     map[string][]Label{},
+	}
+
+	registryMutex.Lock()
+	registry = append(registry, c)
+	registryMutex.Unlock()
+
+	return c
+}
+
+// Inc increases the counter of the given label values by one.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := strings.Join(labelValues, "\xff")
+
+	if _, ok := c.labels[key]; !ok {
+		labels := []Label{}
+		for i, name := range c.labelNames {
+			if i < len(labelValues) {
+				labels = append(labels, Label{Name: name, Value: labelValues[i]})
+			}
+		}
+		c.labels[key] = labels
+	}
+
+	c.values[key]++
+}
+
+// Get returns the current value of the counter of the given label values.
+func (c *CounterVec) Get(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.values[strings.Join(labelValues, "\xff")]
+}
+
+func (c *CounterVec) CollectMetrics(w *Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := []string{}
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 0 {
+		return
+	}
+
+	for _, key := range keys {
+		w.Counter(c.name, c.help, c.values[key], c.labels[key]...)
+	}
+}
+
+// BoolToFloat converts a boolean to 1 or 0 to be used as a metric value.
+func BoolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escape(s string, quoted bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+
+	if quoted {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+
+	return s
+}
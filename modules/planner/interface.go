@@ -6,6 +6,7 @@ type Plannable interface {
 	MakeInlinePlan() *Plan
 	MakeDefaultPlan() *Plan
 	AssignPlan(*Plan)
+	UnassignPlan(*Plan)
 	GetDesiredPlanNames() []string
 	GetPlanCycleHooks() CycleHooks
 }
@@ -18,6 +19,20 @@ func (p *CanAssignPlan) AssignPlan(plan *Plan) {
 	p.assignedPlans = append(p.assignedPlans, plan)
 }
 
+// UnassignPlan detaches the plan from the plannable. It's not safe to be called while
+// the plannable is in use.
+func (p *CanAssignPlan) UnassignPlan(plan *Plan) {
+	plans := []*Plan{}
+
+	for _, assigned := range p.assignedPlans {
+		if assigned != plan {
+			plans = append(plans, assigned)
+		}
+	}
+
+	p.assignedPlans = plans
+}
+
 func (p *CanAssignPlan) GetAssignedPlans() []*Plan {
 	return p.assignedPlans
 }
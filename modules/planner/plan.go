@@ -24,6 +24,13 @@ type Plan struct {
 	// sizes are specified, it'll act like a graph of bars and iterate over them.
 	Size *fluent.FluentSize `json:"size"`
 
+	// Latency determines the latency. Currently, only web server routes use it as the
+	// delay of their responses.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// latencies are specified, it'll act like a graph of bars and iterate over them.
+	Latency *fluent.FluentDuration `json:"latency"`
+
 	// Interval decides how long each plan cycle should last. A value above one second is recommended
 	// but you're free  to use any interval. Default is one second.
 	Interval *fluent.FluentDuration `json:"interval"`
@@ -48,6 +55,7 @@ type Plan struct {
 	plannables        []*Plannable
 	currentCycleValue *CycleValue
 	isDedicated       bool
+	control           *planControl
 }
 
 type Cycle struct {
@@ -58,9 +66,15 @@ type Cycle struct {
 
 type HookFunc func(cycle Cycle) PlanSignal
 
+type FinishFunc func()
+
 type CycleHooks struct {
 	PreSleep  *HookFunc
 	PostSleep *HookFunc
+
+	// Finish is executed once the plan ends - either by running out of cycles or by
+	// being stopped.
+	Finish *FinishFunc
 }
 
 type PlanSignal uint32
@@ -74,16 +88,86 @@ func (p *Plan) ToSubPlan() SubPlan {
 	return SubPlan{
 		Percentage: p.Percentage,
 		Size:       p.Size,
+		Latency:    p.Latency,
 		Interval:   p.Interval,
 		Duration:   p.Duration,
 	}
 }
 
 func (p *Plan) Assign(plannable Plannable) {
+	c := p.getControl()
+	c.mu.Lock()
 	p.plannables = append(p.plannables, &plannable)
+	c.mu.Unlock()
+
 	plannable.AssignPlan(p)
 }
 
+// Unassign detaches the plannable from the plan so that its hooks won't be executed anymore.
+// It waits for the hooks in flight to return.
+func (p *Plan) Unassign(plannable Plannable) {
+	c := p.getControl()
+	c.hooks.Lock()
+	defer c.hooks.Unlock()
+
+	c.mu.Lock()
+
+	plannables := []*Plannable{}
+	for _, pl := range p.plannables {
+		if *pl != plannable {
+			plannables = append(plannables, pl)
+		}
+	}
+	p.plannables = plannables
+
+	c.mu.Unlock()
+
+	plannable.UnassignPlan(p)
+}
+
+// isAssigned reports whether the plannable is assigned to the plan.
+func (p *Plan) isAssigned(plannable Plannable) bool {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, pl := range p.plannables {
+		if *pl == plannable {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runHook executes the hook of the plannable, unless the plannable is unassigned in the
+// meantime.
+func (p *Plan) runHook(plannable Plannable, hook HookFunc, cycle Cycle) PlanSignal {
+	c := p.getControl()
+	c.hooks.RLock()
+	defer c.hooks.RUnlock()
+
+	if !p.isAssigned(plannable) {
+		return PLAN_SIGNAL_CONTINUE
+	}
+
+	return hook(cycle)
+}
+
+// GetPlannables returns the plannables which are assigned to the plan.
+func (p *Plan) GetPlannables() []Plannable {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	plannables := []Plannable{}
+	for _, pl := range p.plannables {
+		plannables = append(plannables, *pl)
+	}
+
+	return plannables
+}
+
 func (p *Plan) MakePrivate() {
 	p.isDedicated = true
 }
@@ -99,13 +183,27 @@ func (p *Plan) Validate() error {
 }
 
 func (p *Plan) GetCurrentValue() *CycleValue {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return p.currentCycleValue
 }
 
 func (p *Plan) SetCurrentValue(cv CycleValue) {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	p.currentCycleValue = &cv
 }
 
+// IsDedicated determines whether the plan is made for a single plannable rather than
+// being defined by the user.
+func (p *Plan) IsDedicated() bool {
+	return p.isDedicated
+}
+
 func (p *Plan) GetPreparedSubPlans() ([]*SubPlan, error) {
 	subPlans := []*SubPlan{}
 
@@ -113,6 +211,7 @@ func (p *Plan) GetPreparedSubPlans() ([]*SubPlan, error) {
 		subPlans = append(subPlans, &SubPlan{
 			Percentage: p.Percentage,
 			Size:       p.Size,
+			Latency:    p.Latency,
 			Interval:   p.Interval,
 			Duration:   p.Duration,
 		})
@@ -134,24 +233,65 @@ func (p *Plan) GetPreparedSubPlans() ([]*SubPlan, error) {
 }
 
 func (p *Plan) Start() {
+	subPlans, _ := p.GetPreparedSubPlans()
+
+	if !p.markStarted(len(subPlans)) {
+		return
+	}
+	defer p.markFinished()
+	defer p.runFinishHooks()
+
 	if logger.Log.Level() == zap.InfoLevel {
 		logger.Log.Info("executing plan...", zap.String("name", *p.Name))
 	} else {
 		plannableNames := []string{}
-		for _, pl := range p.plannables {
-			plr := *pl
-			plannableNames = append(plannableNames, plr.GetName())
+		for _, pl := range p.GetPlannables() {
+			plannableNames = append(plannableNames, pl.GetName())
 		}
-		logger.Log.Debug("executing plan...", zap.String("name", *p.Name), zap.Any("plan", *p), zap.Any("plannables", plannableNames))
+		logger.Log.Debug("executing plan...", zap.String("name", *p.Name), zap.Any("plan", p), zap.Any("plannables", plannableNames))
 	}
 
-	subPlans, _ := p.GetPreparedSubPlans()
+	for i, subPlan := range subPlans {
+		if p.isStopRequested() {
+			logger.Log.Info("plan is stopped", zap.String("name", *p.Name))
+			return
+		}
 
-	for _, subPlan := range subPlans {
+		p.markSubPlan(i)
 		subPlan.Execute()
 	}
 }
 
+// ReplayLastCycle runs the hooks of the last cycle of the plan on the given plannable, so
+// that a plannable which is assigned after the plan is finished ends up like the rest of them.
+func (p *Plan) ReplayLastCycle(plannable Plannable) {
+	cv := p.GetCurrentValue()
+	if cv == nil {
+		return
+	}
+
+	cycle := Cycle{Value: *cv, StartedAt: time.Now()}
+	hooks := plannable.GetPlanCycleHooks()
+
+	for _, hook := range []*HookFunc{hooks.PreSleep, hooks.PostSleep} {
+		if hook != nil {
+			p.runHook(plannable, *hook, cycle)
+		}
+	}
+
+	if hooks.Finish != nil {
+		(*hooks.Finish)()
+	}
+}
+
+func (p *Plan) runFinishHooks() {
+	for _, pl := range p.GetPlannables() {
+		if hook := pl.GetPlanCycleHooks().Finish; hook != nil {
+			(*hook)()
+		}
+	}
+}
+
 func NewPlan(p Plan) Plan {
 	return p
 }
@@ -0,0 +1,344 @@
+package planner
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	PLAN_STATE_PENDING  = "pending"
+	PLAN_STATE_RUNNING  = "running"
+	PLAN_STATE_PAUSED   = "paused"
+	PLAN_STATE_FINISHED = "finished"
+)
+
+// CycleValueOverride forces some of the cycle values of a plan to a fixed value until
+// the given time is reached. Nil values are left untouched.
+type CycleValueOverride struct {
+	Percentage *float64
+	Size       *int64
+	Latency    *time.Duration
+	Until      time.Time
+}
+
+func (o *CycleValueOverride) isExpired() bool {
+	return time.Now().After(o.Until)
+}
+
+func (o *CycleValueOverride) apply(cv CycleValue) CycleValue {
+	if o.Percentage != nil {
+		cv.Percentage = *o.Percentage
+	}
+
+	if o.Size != nil {
+		cv.Size = *o.Size
+	}
+
+	if o.Latency != nil {
+		cv.Latency = *o.Latency
+	}
+
+	return cv
+}
+
+// planControl holds the runtime state of a plan which can be controlled while
+// the plan is being executed.
+type planControl struct {
+	mu            sync.Mutex
+	isStarted     bool
+	isFinished    bool
+	isPaused      bool
+	stopRequested bool
+	done          chan struct{}
+	resumed       chan struct{}
+	interrupt     chan struct{}
+	skipRequested bool
+	override      *CycleValueOverride
+	subPlanIndex  int
+	subPlansCount int
+	cycleIndex    uint64
+
+	// hooks is held by the hooks in flight, so that unassigning a plannable waits for them.
+	hooks sync.RWMutex
+}
+
+var controlInitMutex sync.Mutex
+
+func (p *Plan) getControl() *planControl {
+	controlInitMutex.Lock()
+	defer controlInitMutex.Unlock()
+
+	if p.control == nil {
+		p.control = &planControl{
+			done:      make(chan struct{}),
+			resumed:   make(chan struct{}),
+			interrupt: make(chan struct{}, 1),
+		}
+	}
+
+	return p.control
+}
+
+// GetState returns the execution state of the plan which is one of pending, running, paused
+// or finished.
+func (p *Plan) GetState() string {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.isFinished {
+		return PLAN_STATE_FINISHED
+	}
+
+	if c.isPaused {
+		return PLAN_STATE_PAUSED
+	}
+
+	if c.isStarted {
+		return PLAN_STATE_RUNNING
+	}
+
+	return PLAN_STATE_PENDING
+}
+
+// GetSubPlanIndex returns the zero-based index of the sub-plan under execution along with
+// the total count of the sub-plans.
+func (p *Plan) GetSubPlanIndex() (int, int) {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.subPlanIndex, c.subPlansCount
+}
+
+// GetCycleIndex returns the number of cycles executed in the current sub-plan.
+func (p *Plan) GetCycleIndex() uint64 {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cycleIndex
+}
+
+// Pause holds the plan on its current cycle until it gets resumed.
+func (p *Plan) Pause() {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.isPaused {
+		return
+	}
+
+	c.isPaused = true
+	c.resumed = make(chan struct{})
+}
+
+// Resume continues the execution of a paused plan.
+func (p *Plan) Resume() {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.isPaused {
+		return
+	}
+
+	c.isPaused = false
+	close(c.resumed)
+}
+
+// Override forces the values of the plan to the given ones for the given duration. The
+// current value of the plan is updated immediately.
+func (p *Plan) Override(override CycleValueOverride) {
+	c := p.getControl()
+	c.mu.Lock()
+	c.override = &override
+	c.mu.Unlock()
+
+	if current := p.GetCurrentValue(); current != nil {
+		cv := override.apply(*current)
+		cv.ComputeStaticValues()
+		p.SetCurrentValue(cv)
+	}
+}
+
+// ClearOverride removes the forced values of the plan. Original values will be used
+// from the next cycle.
+func (p *Plan) ClearOverride() {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.override = nil
+}
+
+// GetOverride returns the active override of the plan, if any.
+func (p *Plan) GetOverride() *CycleValueOverride {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.override == nil || c.override.isExpired() {
+		return nil
+	}
+
+	override := *c.override
+
+	return &override
+}
+
+// SkipSubPlan ends the current sub-plan and moves on to the next one.
+func (p *Plan) SkipSubPlan() error {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.isStarted || c.isFinished {
+		return fmt.Errorf("plan is not running")
+	}
+
+	if c.subPlanIndex+1 >= c.subPlansCount {
+		return fmt.Errorf("there is no sub-plan after the current one")
+	}
+
+	c.skipRequested = true
+
+	select {
+	case c.interrupt <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (p *Plan) applyOverride(cv CycleValue) CycleValue {
+	if override := p.GetOverride(); override != nil {
+		return override.apply(cv)
+	}
+
+	return cv
+}
+
+// Stop terminates the execution of the plan and waits for it to end. Hooks of the
+// interrupted cycle won't be executed so each plannable is responsible to clean up
+// on its own.
+func (p *Plan) Stop() {
+	c := p.getControl()
+	c.mu.Lock()
+
+	c.stopRequested = true
+	isRunning := c.isStarted && !c.isFinished
+
+	if c.isPaused {
+		c.isPaused = false
+		close(c.resumed)
+	}
+
+	c.mu.Unlock()
+
+	select {
+	case c.interrupt <- struct{}{}:
+	default:
+	}
+
+	if isRunning {
+		<-c.done
+	}
+}
+
+func (p *Plan) isStopRequested() bool {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stopRequested
+}
+
+// markStarted marks the plan as started unless it's already stopped.
+func (p *Plan) markStarted(subPlansCount int) bool {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopRequested || c.isStarted {
+		return false
+	}
+
+	c.isStarted = true
+	c.subPlansCount = subPlansCount
+
+	return true
+}
+
+func (p *Plan) markFinished() {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.isFinished = true
+	close(c.done)
+}
+
+func (p *Plan) markSubPlan(index int) {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.subPlanIndex = index
+	c.cycleIndex = 0
+	c.skipRequested = false
+
+	// Drain a stale interruption so that it won't cut the next sleep short
+	select {
+	case <-c.interrupt:
+	default:
+	}
+}
+
+func (p *Plan) markCycle() {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cycleIndex++
+}
+
+// consumeSkipRequest reports whether a skip of the current sub-plan is requested.
+func (p *Plan) consumeSkipRequest() bool {
+	c := p.getControl()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	requested := c.skipRequested
+	c.skipRequested = false
+
+	return requested
+}
+
+// sleep waits for the given duration unless it gets interrupted by a skip request.
+func (p *Plan) sleep(d time.Duration) {
+	c := p.getControl()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-c.interrupt:
+	}
+}
+
+// waitWhilePaused blocks until the plan is resumed, if paused.
+func (p *Plan) waitWhilePaused() {
+	c := p.getControl()
+	c.mu.Lock()
+	isPaused := c.isPaused
+	resumed := c.resumed
+	c.mu.Unlock()
+
+	if isPaused {
+		<-resumed
+	}
+}
@@ -0,0 +1,36 @@
+package planner
+
+import (
+	"kermoo/modules/metrics"
+)
+
+var _ metrics.Collector = &Plan{}
+
+// CollectMetrics exposes the current value and the execution progress of the plan.
+func (p *Plan) CollectMetrics(w *metrics.Writer) {
+	if p.Name == nil {
+		return
+	}
+
+	label := metrics.Label{Name: "plan", Value: *p.Name}
+	subPlan, _ := p.GetSubPlanIndex()
+	state := p.GetState()
+
+	w.Gauge("kermoo_plan_running", "Whether the plan is running (1) or not (0) - paused plans are considered running.", metrics.BoolToFloat(state == PLAN_STATE_RUNNING || state == PLAN_STATE_PAUSED), label)
+	w.Gauge("kermoo_plan_paused", "Whether the plan is paused (1) or not (0).", metrics.BoolToFloat(state == PLAN_STATE_PAUSED), label)
+	w.Gauge("kermoo_plan_sub_plan_index", "Zero-based index of the sub-plan under execution.", float64(subPlan), label)
+	w.Gauge("kermoo_plan_cycle_index", "Number of cycles executed in the current sub-plan.", float64(p.GetCycleIndex()), label)
+
+	cv := p.GetCurrentValue()
+	if cv == nil {
+		return
+	}
+
+	w.Gauge("kermoo_plan_percentage", "Current percentage of the plan.", cv.Percentage, label)
+	w.Gauge("kermoo_plan_size_bytes", "Current size of the plan in bytes.", float64(cv.Size), label)
+	w.Gauge("kermoo_plan_latency_seconds", "Current latency of the plan in seconds.", cv.Latency.Seconds(), label)
+
+	if cv.ComputedPercentageChance != nil {
+		w.Gauge("kermoo_plan_succeeding", "Whether the current cycle of the plan is succeeding (1) or failing (0).", metrics.BoolToFloat(*cv.ComputedPercentageChance), label)
+	}
+}
@@ -26,6 +26,13 @@ type SubPlan struct {
 	// sizes are specified, it'll act like a graph of bars and iterate over them.
 	Size *fluent.FluentSize `json:"size"`
 
+	// Latency determines the latency. Currently, only web server routes use it as the
+	// delay of their responses.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// latencies are specified, it'll act like a graph of bars and iterate over them.
+	Latency *fluent.FluentDuration `json:"latency"`
+
 	// Interval decides how long each sub-plan cycle should last. A value above one second is recommended
 	// but you're free  to use any interval. Default is one second.
 	Interval *fluent.FluentDuration `json:"interval"`
@@ -44,6 +51,7 @@ type SubPlan struct {
 type CycleValue struct {
 	Percentage               float64
 	Size                     int64
+	Latency                  time.Duration
 	ComputedPercentageChance *bool
 }
 
@@ -71,13 +79,27 @@ func (s *SubPlan) computeCycleValues() ([]CycleValue, error) {
 		count = len(percentages)
 	}
 
+	var latencies []time.Duration
+	if s.Latency != nil {
+		latencies = s.Latency.GetArray()
+	}
+
+	if len(latencies) > count {
+		count = len(latencies)
+	}
+
 	if len(sizes) > 0 && len(percentages) > 0 && len(sizes) != len(percentages) {
 		return nil, fmt.Errorf("both size and percentage are set while the count of individual items does not match together")
 	}
 
+	if len(latencies) > 0 && ((len(sizes) > 0 && len(sizes) != len(latencies)) || (len(percentages) > 0 && len(percentages) != len(latencies))) {
+		return nil, fmt.Errorf("latency is set along with size or percentage while the count of individual items does not match together")
+	}
+
 	for i := 0; i < count; i++ {
 		percentage := float64(0)
 		size := int64(0)
+		latency := time.Duration(0)
 
 		if len(percentages) >= i+1 {
 			percentage = percentages[i]
@@ -87,9 +109,14 @@ func (s *SubPlan) computeCycleValues() ([]CycleValue, error) {
 			size = sizes[i]
 		}
 
+		if len(latencies) >= i+1 {
+			latency = latencies[i]
+		}
+
 		cycleValues = append(cycleValues, CycleValue{
 			Percentage: percentage,
 			Size:       size,
+			Latency:    latency,
 		})
 	}
 
@@ -132,14 +159,16 @@ func (s *SubPlan) Prepare() error {
 func (s *SubPlan) Execute() {
 	for {
 		for _, cycleValue := range s.cycleValues {
-			if !s.NextCycle() {
+			if s.relatedPlan.isStopRequested() || !s.NextCycle() {
 				return
 			}
 
 			startedAt := time.Now()
 
+			cycleValue = s.relatedPlan.applyOverride(cycleValue)
 			cycleValue.ComputeStaticValues()
 			s.relatedPlan.SetCurrentValue(cycleValue)
+			s.relatedPlan.markCycle()
 
 			logger.Log.Info("executing preSleep hooks...", zap.String("plan", *s.relatedPlan.Name))
 			if !s.RunPlannableHooks(startedAt, cycleValue, "preSleep") {
@@ -147,7 +176,12 @@ func (s *SubPlan) Execute() {
 				return
 			}
 
-			time.Sleep(s.getInterval())
+			s.relatedPlan.sleep(s.getInterval())
+			s.relatedPlan.waitWhilePaused()
+
+			if s.relatedPlan.isStopRequested() {
+				return
+			}
 
 			logger.Log.Info("executing postSleep hooks...", zap.String("plan", *s.relatedPlan.Name))
 			if !s.RunPlannableHooks(startedAt, cycleValue, "postSleep") {
@@ -155,6 +189,11 @@ func (s *SubPlan) Execute() {
 				return
 			}
 
+			if s.relatedPlan.consumeSkipRequest() {
+				logger.Log.Info("skipping to the next sub-plan by request", zap.String("plan", *s.relatedPlan.Name))
+				return
+			}
+
 			if s.getInterval() == 0 {
 				logger.Log.Info("pausing plan due to zero interval", zap.String("plan", *s.relatedPlan.Name))
 				return
@@ -186,9 +225,7 @@ func (s *SubPlan) NextCycle() bool {
 }
 
 func (s *SubPlan) RunPlannableHooks(startedAt time.Time, cv CycleValue, hookType string) bool {
-	for _, pl := range s.relatedPlan.plannables {
-		plannable := *pl
-
+	for _, plannable := range s.relatedPlan.GetPlannables() {
 		var hook *HookFunc
 		if hookType == "preSleep" {
 			hook = plannable.GetPlanCycleHooks().PreSleep
@@ -197,8 +234,7 @@ func (s *SubPlan) RunPlannableHooks(startedAt time.Time, cv CycleValue, hookType
 		}
 
 		if hook != nil {
-			executable := *hook
-			value := executable(Cycle{
+			value := s.relatedPlan.runHook(plannable, *hook, Cycle{
 				Value:     cv,
 				StartedAt: startedAt,
 				TimeSpent: time.Since(startedAt),
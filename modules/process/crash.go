@@ -0,0 +1,212 @@
+package process
+
+import (
+	"fmt"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/planner"
+	"math/rand"
+	"os"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+)
+
+const (
+	CRASH_TYPE_EXIT     = "exit"
+	CRASH_TYPE_PANIC    = "panic"
+	CRASH_TYPE_SEGFAULT = "segfault"
+	CRASH_TYPE_KILL     = "kill"
+	CRASH_TYPE_OOM      = "oom"
+)
+
+// oomChunkSize is the size of the chunks which are allocated to run out of memory.
+const oomChunkSize = 64 * 1024 * 1024
+
+// nilPointer is dereferenced to cause a segmentation fault.
+var nilPointer *int
+
+// Ensure that ProcessCrash is implementing Plannable
+var _ planner.Plannable = &ProcessCrash{}
+
+// ProcessCrash crashes the process in one of the ways defined in Types on failing cycles of
+// its plan.
+type ProcessCrash struct {
+	planner.CanAssignPlan
+
+	// PlanRefs is an optional list of plan names. It can used to avoid redundant
+	// re-declearing of plans in large-scale configurations.
+	// PlanRefs overrides Percentage, Interval and Duration fields are overrided in favor
+	// of the one defined in the referenced plan.
+	PlanRefs []string `json:"planRefs"`
+
+	// Percentage determines the chance of crashing on each cycle. 0 means never crashing
+	// and 100 means crashing on the first cycle.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// percentages are specified, it'll act like a graph of bars and iterate over them.
+	Percentage fluent.FluentFloat `json:"percentage"`
+
+	// Interval decides how long each cycle lasts, e.g. a percentage of 10 on an interval of
+	// one minute crashes the process once in ten minutes on average.
+	//
+	// Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Duration defines the duration of the entire crash plan. Leave it empty for life-long
+	// running or specify one to end it after that and last decision will be happening for ever.
+	// In fact, Duration/Interval determines the number of cycle, if defined. Default is empty
+	// for unlimited activity.
+	Duration *fluent.FluentDuration `json:"duration"`
+
+	// Types defines how the process can crash. One of them is picked randomly on crashing:
+	//
+	// - exit: exits with one of ExitCodes.
+	// - panic: panics with a Go stack trace, which exits with 2.
+	// - segfault: dereferences a nil pointer and aborts with SIGABRT, so that a core dump is
+	//   produced if it's enabled by ulimit.
+	// - kill: kills itself by SIGKILL, which exits with 137.
+	// - oom: allocates memory until it's killed by the OOM killer or the Go runtime.
+	//
+	// Default is all of them.
+	Types []string `json:"types"`
+
+	// ExitCodes defines the exit codes which one of them is picked randomly on exit crashes.
+	//
+	// Default is 1.
+	ExitCodes []uint `json:"exitCodes"`
+}
+
+func (pc *ProcessCrash) GetName() string {
+	return "process-crash"
+}
+
+func (pc *ProcessCrash) GetTypes() []string {
+	if len(pc.Types) > 0 {
+		return pc.Types
+	}
+
+	return []string{CRASH_TYPE_EXIT, CRASH_TYPE_PANIC, CRASH_TYPE_SEGFAULT, CRASH_TYPE_KILL, CRASH_TYPE_OOM}
+}
+
+// PickType picks one of the crash types randomly.
+func (pc *ProcessCrash) PickType() string {
+	types := pc.GetTypes()
+
+	return types[rand.Intn(len(types))]
+}
+
+// PickExitCode picks one of the exit codes randomly.
+func (pc *ProcessCrash) PickExitCode() int {
+	if len(pc.ExitCodes) == 0 {
+		return 1
+	}
+
+	return int(pc.ExitCodes[rand.Intn(len(pc.ExitCodes))])
+}
+
+func (pc *ProcessCrash) Validate() error {
+	if len(pc.PlanRefs) == 0 && !pc.HasInlinePlan() {
+		return fmt.Errorf("no percentage or plan refs is set")
+	}
+
+	for _, t := range pc.Types {
+		switch t {
+		case CRASH_TYPE_EXIT, CRASH_TYPE_PANIC, CRASH_TYPE_SEGFAULT, CRASH_TYPE_KILL, CRASH_TYPE_OOM:
+		default:
+			return fmt.Errorf("crash type %s is not supported", t)
+		}
+	}
+
+	for _, code := range pc.ExitCodes {
+		if code > 255 {
+			return fmt.Errorf("exit code %d is out of range of 0-255", code)
+		}
+	}
+
+	if pc.HasInlinePlan() {
+		if err := pc.MakeInlinePlan().Validate(); err != nil {
+			return fmt.Errorf("crafted plan validation failed: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Crash crashes the process in the given way.
+func (pc *ProcessCrash) Crash(t string) {
+	logger.Log.Warn("crashing the process on purpose...", zap.String("type", t))
+	_ = logger.Log.Sync()
+
+	switch t {
+	case CRASH_TYPE_EXIT:
+		os.Exit(pc.PickExitCode())
+	case CRASH_TYPE_PANIC:
+		panic("crashing the process on purpose")
+	case CRASH_TYPE_SEGFAULT:
+		// Abort instead of exiting on the fatal panic, along with the stack of all goroutines
+		debug.SetTraceback("crash")
+		*nilPointer = 0
+	case CRASH_TYPE_KILL:
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			_ = p.Kill()
+		}
+	case CRASH_TYPE_OOM:
+		chunks := [][]byte{}
+
+		for {
+			chunk := make([]byte, oomChunkSize)
+
+			// Touch the pages so that they're actually resident
+			for i := 0; i < len(chunk); i += os.Getpagesize() {
+				chunk[i] = 1
+			}
+
+			chunks = append(chunks, chunk)
+		}
+	}
+}
+
+func (pc *ProcessCrash) HasInlinePlan() bool {
+	return pc.MakeInlinePlan() != nil
+}
+
+func (pc *ProcessCrash) MakeInlinePlan() *planner.Plan {
+	if pc.Percentage.GetParsedValue() == nil {
+		return nil
+	}
+
+	plan := planner.NewPlan(planner.Plan{
+		Percentage: &pc.Percentage,
+		Interval:   pc.Interval,
+		Duration:   pc.Duration,
+	})
+
+	return &plan
+}
+
+func (pc *ProcessCrash) MakeDefaultPlan() *planner.Plan {
+	return nil
+}
+
+func (pc *ProcessCrash) GetDesiredPlanNames() []string {
+	return pc.PlanRefs
+}
+
+func (pc *ProcessCrash) GetPlanCycleHooks() planner.CycleHooks {
+	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		for _, plan := range pc.GetAssignedPlans() {
+			cv := plan.GetCurrentValue()
+
+			if cv != nil && cv.ComputedPercentageChance != nil && !*cv.ComputedPercentageChance {
+				pc.Crash(pc.PickType())
+			}
+		}
+
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	return planner.CycleHooks{
+		PreSleep: &preSleep,
+	}
+}
@@ -0,0 +1,172 @@
+package process
+
+import (
+	"fmt"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// hangLock is held by the hangs, so that everything waiting on it is deadlocked until
+	// the hang is released.
+	hangLock sync.RWMutex
+	hangMu   sync.Mutex
+	hung     atomic.Bool
+)
+
+// Ensure that ProcessHang is implementing Plannable
+var _ planner.Plannable = &ProcessHang{}
+
+var _ metrics.Collector = &ProcessHang{}
+
+// ProcessHang freezes the application on failing cycles of its plan, while the process
+// stays alive. The web and tcp servers hold the requests and connections, the udp servers
+// leave the datagrams unanswered and the signals and the "app is alive" logs are held too.
+// The admin server is not affected, so the hang can be inspected.
+type ProcessHang struct {
+	planner.CanAssignPlan
+
+	// PlanRefs is an optional list of plan names. It can used to avoid redundant
+	// re-declearing of plans in large-scale configurations.
+	// PlanRefs overrides Percentage, Interval and Duration fields are overrided in favor
+	// of the one defined in the referenced plan.
+	PlanRefs []string `json:"planRefs"`
+
+	// Percentage determines the chance of hanging on each cycle. 0 means never hanging and
+	// 100 means hanging for ever. The application is released on the next succeeding cycle.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// percentages are specified, it'll act like a graph of bars and iterate over them.
+	Percentage fluent.FluentFloat `json:"percentage"`
+
+	// Interval decides how long each desicion to hang or not should last.
+	//
+	// Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Duration defines the duration of the entire hang plan. Leave it empty for life-long
+	// running or specify one to end it after that and last decision will be happening for ever.
+	// In fact, Duration/Interval determines the number of cycle, if defined. Default is empty
+	// for unlimited activity.
+	Duration *fluent.FluentDuration `json:"duration"`
+}
+
+// IsHung reports whether the application is hung at the moment.
+func IsHung() bool {
+	return hung.Load()
+}
+
+// WaitWhileHung blocks the caller as long as the application is hung.
+func WaitWhileHung() {
+	hangLock.RLock()
+	defer hangLock.RUnlock()
+}
+
+// Hang freezes the application until it's released.
+func Hang() {
+	hangMu.Lock()
+	defer hangMu.Unlock()
+
+	if hung.Load() {
+		return
+	}
+
+	logger.Log.Warn("hanging the application on purpose...")
+
+	hangLock.Lock()
+	hung.Store(true)
+}
+
+// Release unfreezes the application, if it's hung.
+func Release() {
+	hangMu.Lock()
+	defer hangMu.Unlock()
+
+	if !hung.Load() {
+		return
+	}
+
+	logger.Log.Info("releasing the application from hang")
+
+	hung.Store(false)
+	hangLock.Unlock()
+}
+
+func (ph *ProcessHang) GetName() string {
+	return "process-hang"
+}
+
+func (ph *ProcessHang) Validate() error {
+	if len(ph.PlanRefs) == 0 && !ph.HasInlinePlan() {
+		return fmt.Errorf("no percentage or plan refs is set")
+	}
+
+	if ph.HasInlinePlan() {
+		if err := ph.MakeInlinePlan().Validate(); err != nil {
+			return fmt.Errorf("crafted plan validation failed: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (ph *ProcessHang) HasInlinePlan() bool {
+	return ph.MakeInlinePlan() != nil
+}
+
+func (ph *ProcessHang) MakeInlinePlan() *planner.Plan {
+	if ph.Percentage.GetParsedValue() == nil {
+		return nil
+	}
+
+	plan := planner.NewPlan(planner.Plan{
+		Percentage: &ph.Percentage,
+		Interval:   ph.Interval,
+		Duration:   ph.Duration,
+	})
+
+	return &plan
+}
+
+func (ph *ProcessHang) MakeDefaultPlan() *planner.Plan {
+	return nil
+}
+
+func (ph *ProcessHang) GetDesiredPlanNames() []string {
+	return ph.PlanRefs
+}
+
+func (ph *ProcessHang) GetPlanCycleHooks() planner.CycleHooks {
+	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		shouldHang := false
+
+		for _, plan := range ph.GetAssignedPlans() {
+			cv := plan.GetCurrentValue()
+
+			if cv != nil && cv.ComputedPercentageChance != nil && !*cv.ComputedPercentageChance {
+				shouldHang = true
+			}
+		}
+
+		if shouldHang {
+			Hang()
+		} else {
+			Release()
+		}
+
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	return planner.CycleHooks{
+		PreSleep: &preSleep,
+	}
+}
+
+// CollectMetrics exposes whether the application is hung.
+func (ph *ProcessHang) CollectMetrics(w *metrics.Writer) {
+	w.Gauge("kermoo_process_hung", "Whether the application is hung on purpose (1) or not (0).", metrics.BoolToFloat(IsHung()))
+}
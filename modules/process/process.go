@@ -1,6 +1,7 @@
 package process
 
 import (
+	"fmt"
 	"kermoo/modules/fluent"
 	"kermoo/modules/logger"
 	"kermoo/modules/planner"
@@ -22,6 +23,23 @@ type Process struct {
 	// Exit optionally simulates sudden termination of the process in the given time with
 	// the given exit code.
 	Exit *ProcessExit `json:"exit"`
+
+	// Crash optionally crashes the process in various ways, such as panics and segmentation
+	// faults, on failing cycles of a plan.
+	Crash *ProcessCrash `json:"crash"`
+
+	// Hang optionally freezes the application while the process stays alive, on failing
+	// cycles of a plan.
+	Hang *ProcessHang `json:"hang"`
+
+	// Startup optionally simulates a slow and possibly failing initialization, during which
+	// the default /readyz and /startupz routes are not ready.
+	Startup *ProcessStartup `json:"startup"`
+
+	// Shutdown optionally controls how the process behaves on SIGTERM and SIGINT signals.
+	//
+	// By default, the web servers are drained gracefully and the process exits with 0.
+	Shutdown *ProcessShutdown `json:"shutdown"`
 }
 
 type ProcessExit struct {
@@ -45,6 +63,30 @@ func (p Process) GetDesiredPlanNames() []string {
 }
 
 func (p Process) Validate() error {
+	if p.Startup != nil {
+		if err := p.Startup.Validate(); err != nil {
+			return fmt.Errorf("startup is invalid: %v", err)
+		}
+	}
+
+	if p.Hang != nil {
+		if err := p.Hang.Validate(); err != nil {
+			return fmt.Errorf("hang is invalid: %v", err)
+		}
+	}
+
+	if p.Crash != nil {
+		if err := p.Crash.Validate(); err != nil {
+			return fmt.Errorf("crash is invalid: %v", err)
+		}
+	}
+
+	if p.Shutdown != nil {
+		if err := p.Shutdown.Validate(); err != nil {
+			return fmt.Errorf("shutdown is invalid: %v", err)
+		}
+	}
+
 	return nil
 }
 
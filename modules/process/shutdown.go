@@ -0,0 +1,70 @@
+package process
+
+import (
+	"fmt"
+	"kermoo/modules/fluent"
+	"time"
+)
+
+type ProcessShutdown struct {
+	// GracePeriod determines how long the web servers can take to serve their in-flight
+	// requests before the remaining connections are closed.
+	//
+	// Default is 10 seconds, which fits in the default termination grace period of Kubernetes.
+	GracePeriod *fluent.FluentDuration `json:"gracePeriod"`
+
+	// KeepServing determines how long the process keeps accepting new traffic after the
+	// signal, before starting to drain. It's useful to see whether the endpoints are removed
+	// in time, e.g. by a preStop hook.
+	//
+	// Default is draining right away.
+	KeepServing *fluent.FluentDuration `json:"keepServing"`
+
+	// ExitDelay determines how long the process takes to exit after everything is stopped.
+	//
+	// Default is exiting right away.
+	ExitDelay *fluent.FluentDuration `json:"exitDelay"`
+
+	// ExitCode indicates the exit code of the process on shutdown.
+	//
+	// Default is 0.
+	ExitCode uint `json:"exitCode"`
+
+	// IgnoreSigterm makes the process ignore SIGTERM signals, so that it's only stopped by
+	// SIGKILL after the termination grace period. SIGINT is still respected.
+	//
+	// Default is false.
+	IgnoreSigterm bool `json:"ignoreSigterm"`
+}
+
+func (ps *ProcessShutdown) GetGracePeriod() time.Duration {
+	if ps.GracePeriod != nil {
+		return ps.GracePeriod.Get()
+	}
+
+	return 10 * time.Second
+}
+
+func (ps *ProcessShutdown) GetKeepServing() time.Duration {
+	if ps.KeepServing != nil {
+		return ps.KeepServing.Get()
+	}
+
+	return 0
+}
+
+func (ps *ProcessShutdown) GetExitDelay() time.Duration {
+	if ps.ExitDelay != nil {
+		return ps.ExitDelay.Get()
+	}
+
+	return 0
+}
+
+func (ps *ProcessShutdown) Validate() error {
+	if ps.ExitCode > 255 {
+		return fmt.Errorf("exit code %d is out of range of 0-255", ps.ExitCode)
+	}
+
+	return nil
+}
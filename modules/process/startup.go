@@ -0,0 +1,90 @@
+package process
+
+import (
+	"fmt"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/utils"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// initializing indicates whether the application is still in its startup phase.
+var initializing atomic.Bool
+
+// ProcessStartup simulates the initialization of the application. The routes which wait
+// for the startup, like the default /readyz and /startupz, are not ready until it completes.
+type ProcessStartup struct {
+	// Duration determines how long the initialization takes.
+	//
+	// Default is no initialization time.
+	Duration *fluent.FluentDuration `json:"duration"`
+
+	// FailurePercentage determines the chance of the initialization to fail at the end of it,
+	// which exits the process with ExitCode.
+	//
+	// Default is never failing.
+	FailurePercentage *fluent.FluentFloat `json:"failurePercentage"`
+
+	// ExitCode indicates the exit code of the process when the initialization fails.
+	//
+	// Default is 1.
+	ExitCode *uint `json:"exitCode"`
+}
+
+// IsInitializing reports whether the application is still in its startup phase.
+func IsInitializing() bool {
+	return initializing.Load()
+}
+
+func (ps *ProcessStartup) GetExitCode() int {
+	if ps.ExitCode != nil {
+		return int(*ps.ExitCode)
+	}
+
+	return 1
+}
+
+func (ps *ProcessStartup) Validate() error {
+	if ps.ExitCode != nil && *ps.ExitCode > 255 {
+		return fmt.Errorf("exit code %d is out of range of 0-255", *ps.ExitCode)
+	}
+
+	if ps.FailurePercentage != nil {
+		if p := ps.FailurePercentage.Get(); p < 0 || p > 100 {
+			return fmt.Errorf("failure percentage %v is out of range of 0-100", p)
+		}
+	}
+
+	return nil
+}
+
+// Begin puts the application in its startup phase and completes it on the background.
+func (ps *ProcessStartup) Begin() {
+	initializing.Store(true)
+
+	go ps.complete()
+}
+
+// complete waits for the initialization time and then either completes the startup phase
+// or exits the process as a failed initialization.
+func (ps *ProcessStartup) complete() {
+	if ps.Duration != nil {
+		dur := ps.Duration.Get()
+		logger.Log.Info("initializing...", zap.Duration("duration", dur))
+		time.Sleep(dur)
+	}
+
+	if ps.FailurePercentage != nil && !utils.PercentageToBoolean(ps.FailurePercentage.Get()) {
+		logger.Log.Error("initialization failed on purpose", zap.Int("exit_code", ps.GetExitCode()))
+		_ = logger.Log.Sync()
+
+		os.Exit(ps.GetExitCode())
+	}
+
+	initializing.Store(false)
+	logger.Log.Info("initialized.")
+}
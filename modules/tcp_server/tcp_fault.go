@@ -0,0 +1,98 @@
+package tcp_server
+
+import (
+	"fmt"
+	"kermoo/modules/fluent"
+	"math/rand"
+)
+
+const (
+	TCP_FAULT_REFUSE = "refuse"
+	TCP_FAULT_RESET  = "reset"
+	TCP_FAULT_HANG   = "hang"
+	TCP_FAULT_CLOSE  = "close"
+)
+
+type TcpServerFault struct {
+	// PlanRefs is an optional list of plan names. It can used to avoid redundant
+	// re-declearing of plans in large-scale configurations.
+	// PlanRefs overrides Percentage, Interval and Duration fields are overrided in favor
+	// of the one defined in the referenced plan.
+	PlanRefs []string `json:"planRefs"`
+
+	// Percentage determines the chance of failing. 0 means no not failure at all and 100
+	// means always failing. By failing, we mean the server will misbehave in one of the
+	// ways defined in Types.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// percentages are specified, it'll act like a graph of bars and iterate over them.
+	Percentage fluent.FluentFloat `json:"percentage"`
+
+	// Interval decides how long each desicion to stay failing or serving should last.
+	// A value above one second is recommended but you're free  to use any interval.
+	// Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Duration defines the duration of the entire server. Leave it empty for
+	// life-long running or specify one to end the module completely after that and last decision
+	// will be happening for ever.
+	// In fact, Duration/Interval determines the number of cycle, if defined. Default is empty
+	// for unlimited activity.
+	Duration *fluent.FluentDuration `json:"duration"`
+
+	// Types defines how the server can misbehave when it's in failing state. One of them is
+	// picked randomly for each failing cycle:
+	//
+	// - refuse: stops listening so that the connections are refused.
+	// - reset: accepts the connections and resets them right away (RST).
+	// - hang: accepts the connections but never responds.
+	// - close: serves the connections but closes them after CloseAfterBytes bytes are sent.
+	//
+	// Default is all of them.
+	Types []string `json:"types"`
+
+	// CloseAfterBytes determines how many bytes should be sent before closing the connection
+	// in the close fault.
+	//
+	// Default is zero, so the connection is closed right after being accepted.
+	CloseAfterBytes *int64 `json:"closeAfterBytes"`
+}
+
+func (tf *TcpServerFault) GetTypes() []string {
+	if len(tf.Types) > 0 {
+		return tf.Types
+	}
+
+	return []string{TCP_FAULT_REFUSE, TCP_FAULT_RESET, TCP_FAULT_HANG, TCP_FAULT_CLOSE}
+}
+
+func (tf *TcpServerFault) GetCloseAfterBytes() int64 {
+	if tf.CloseAfterBytes != nil {
+		return *tf.CloseAfterBytes
+	}
+
+	return 0
+}
+
+// PickType picks one of the fault types randomly.
+func (tf *TcpServerFault) PickType() string {
+	types := tf.GetTypes()
+
+	return types[rand.Intn(len(types))]
+}
+
+func (tf *TcpServerFault) Validate() error {
+	for _, t := range tf.Types {
+		switch t {
+		case TCP_FAULT_REFUSE, TCP_FAULT_RESET, TCP_FAULT_HANG, TCP_FAULT_CLOSE:
+		default:
+			return fmt.Errorf("fault type %s is not supported", t)
+		}
+	}
+
+	if tf.CloseAfterBytes != nil && *tf.CloseAfterBytes < 0 {
+		return fmt.Errorf("close after bytes can not be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,378 @@
+package tcp_server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"kermoo/modules/process"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gosimple/slug"
+	"go.uber.org/zap"
+)
+
+var _ planner.Plannable = &TcpServer{}
+var _ metrics.Collector = &TcpServer{}
+
+var faultsCounter = metrics.NewCounterVec(
+	"kermoo_tcpserver_faults_total",
+	"Number of connections which are faulted by the tcp servers.",
+	"tcpserver", "fault",
+)
+
+var errLimitReached = errors.New("limit of bytes to be sent is reached")
+
+type TcpContent struct {
+	// Banner defines a text to be sent as soon as a connection is accepted.
+	//
+	// Default is no banner.
+	Banner string `json:"banner"`
+
+	// Echo indicates the received bytes to be sent back to the client. When it's disabled,
+	// the connection is closed after sending the banner.
+	//
+	// Default is true.
+	Echo *bool `json:"echo"`
+}
+
+func (tc *TcpContent) ShouldEcho() bool {
+	return tc.Echo == nil || *tc.Echo
+}
+
+type TcpServer struct {
+	planner.CanAssignPlan
+
+	// Interface defines the network interface which the server should listen on. Default
+	// is 0.0.0.0 but you're free to define another one like 127.0.0.1.
+	Interface *string `json:"interface"`
+
+	// Port defines the port which the server should listen on. It's required.
+	Port *int32 `json:"port"`
+
+	// Content defines how the server should serve the connections in healthy state.
+	// Default is echoing the received bytes back.
+	Content TcpContent `json:"content"`
+
+	// Fault specifies how the server should fail. Default is no failure.
+	Fault *TcpServerFault `json:"fault"`
+
+	listener    net.Listener
+	conns       map[net.Conn]struct{}
+	fault       string
+	accepting   chan struct{}
+	isListening atomic.Bool
+	mu          sync.Mutex
+}
+
+func (ts *TcpServer) GetName() string {
+	return slug.Make(fmt.Sprintf("tcpserver-%s-%d", ts.GetInterface(), ts.GetPort()))
+}
+
+func (ts *TcpServer) GetPort() int32 {
+	if ts.Port != nil {
+		return *ts.Port
+	}
+
+	return 0
+}
+
+func (ts *TcpServer) GetInterface() string {
+	if ts.Interface != nil {
+		return *ts.Interface
+	}
+
+	return "0.0.0.0"
+}
+
+func (ts *TcpServer) GetAddress() string {
+	return fmt.Sprintf("%s:%d", ts.GetInterface(), ts.GetPort())
+}
+
+func (ts *TcpServer) Validate() error {
+	if ts.GetPort() <= 0 || ts.GetPort() > 65535 {
+		return fmt.Errorf("port is required and must be between 1 and 65535")
+	}
+
+	if ts.Fault != nil {
+		if err := ts.Fault.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsListening reports whether the server is up and accepting connections.
+func (ts *TcpServer) IsListening() bool {
+	return ts.isListening.Load()
+}
+
+// GetFault returns the fault which is applied on the upcoming connections, or an empty
+// string when the server is healthy.
+func (ts *TcpServer) GetFault() string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.fault
+}
+
+// SetFault sets the fault to be applied on the upcoming connections. The refuse fault
+// stops the listener while the others keep it listening.
+func (ts *TcpServer) SetFault(fault string) error {
+	ts.mu.Lock()
+	ts.fault = fault
+	ts.mu.Unlock()
+
+	if fault == TCP_FAULT_REFUSE {
+		ts.StopListening()
+		return nil
+	}
+
+	if !ts.IsListening() {
+		return ts.Listen()
+	}
+
+	return nil
+}
+
+// Listen starts listening and serving the connections on the background.
+func (ts *TcpServer) Listen() error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.listener != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", ts.GetAddress())
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Info("listening tcp server...", zap.String("tcpserver", ts.GetName()))
+
+	ts.listener = listener
+	ts.accepting = make(chan struct{})
+	ts.isListening.Store(true)
+
+	if ts.conns == nil {
+		ts.conns = map[net.Conn]struct{}{}
+	}
+
+	go ts.accept(listener, ts.accepting)
+
+	return nil
+}
+
+// StopListening stops accepting new connections. Already accepted connections are kept.
+func (ts *TcpServer) StopListening() {
+	ts.mu.Lock()
+	listener := ts.listener
+	accepting := ts.accepting
+	ts.listener = nil
+	ts.mu.Unlock()
+
+	if listener == nil {
+		return
+	}
+
+	logger.Log.Info("tcp server stops listening", zap.String("tcpserver", ts.GetName()))
+
+	listener.Close()
+	<-accepting
+	ts.isListening.Store(false)
+}
+
+// Stop stops listening and closes all of the connections.
+func (ts *TcpServer) Stop() {
+	ts.StopListening()
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for conn := range ts.conns {
+		conn.Close()
+	}
+}
+
+func (ts *TcpServer) accept(listener net.Listener, accepting chan struct{}) {
+	defer close(accepting)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Log.Error("failed on accepting tcp connection", zap.String("tcpserver", ts.GetName()), zap.Error(err))
+			}
+			return
+		}
+
+		ts.mu.Lock()
+		ts.conns[conn] = struct{}{}
+		fault := ts.fault
+		ts.mu.Unlock()
+
+		go ts.handle(conn, fault)
+	}
+}
+
+func (ts *TcpServer) handle(conn net.Conn, fault string) {
+	defer func() {
+		conn.Close()
+
+		ts.mu.Lock()
+		delete(ts.conns, conn)
+		ts.mu.Unlock()
+	}()
+
+	// Connections are accepted by the kernel anyway, but not served while hung
+	process.WaitWhileHung()
+
+	if fault != "" {
+		faultsCounter.Inc(ts.GetName(), fault)
+	}
+
+	switch fault {
+	case TCP_FAULT_RESET:
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			// Discard the unsent data and send RST on close
+			_ = tcpConn.SetLinger(0)
+		}
+	case TCP_FAULT_HANG:
+		_, _ = io.Copy(io.Discard, conn)
+	case TCP_FAULT_CLOSE:
+		// Nothing is going to be sent, so there's no need to wait for the client
+		if ts.Fault.GetCloseAfterBytes() == 0 {
+			return
+		}
+
+		ts.serve(conn, &limitedWriter{w: conn, remaining: ts.Fault.GetCloseAfterBytes()})
+	default:
+		ts.serve(conn, conn)
+	}
+}
+
+func (ts *TcpServer) serve(r io.Reader, w io.Writer) {
+	if ts.Content.Banner != "" {
+		if _, err := io.WriteString(w, ts.Content.Banner); err != nil {
+			return
+		}
+	}
+
+	if ts.Content.ShouldEcho() {
+		_, _ = io.Copy(w, r)
+	}
+}
+
+func (ts *TcpServer) HasInlinePlan() bool {
+	return ts.MakeInlinePlan() != nil
+}
+
+func (ts *TcpServer) MakeInlinePlan() *planner.Plan {
+	if ts.Fault == nil {
+		return nil
+	}
+
+	plan := planner.NewPlan(planner.Plan{
+		Percentage: &ts.Fault.Percentage,
+		Interval:   ts.Fault.Interval,
+		Duration:   ts.Fault.Duration,
+	})
+
+	return &plan
+}
+
+// Create a lifetime-long plan to serve tcp server
+func (ts *TcpServer) MakeDefaultPlan() *planner.Plan {
+	plan := planner.NewPlan(planner.Plan{})
+
+	// Value of 0.0 indicates that the server will never fail.
+	plan.Percentage = fluent.NewMustFluentFloat("0.0")
+
+	return &plan
+}
+
+func (ts *TcpServer) GetDesiredPlanNames() []string {
+	if ts.Fault == nil {
+		return nil
+	}
+
+	return ts.Fault.PlanRefs
+}
+
+func (ts *TcpServer) getPlanPercentageState() bool {
+	for _, plan := range ts.GetAssignedPlans() {
+		if !*plan.GetCurrentValue().ComputedPercentageChance {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (ts *TcpServer) GetPlanCycleHooks() planner.CycleHooks {
+	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		fault := ""
+
+		if !ts.getPlanPercentageState() && ts.Fault != nil {
+			fault = ts.Fault.PickType()
+		}
+
+		if err := ts.SetFault(fault); err != nil {
+			logger.Log.Error("error while listening to tcp server", zap.String("tcpserver", ts.GetName()), zap.Error(err))
+		}
+
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	return planner.CycleHooks{
+		PreSleep: &preSleep,
+	}
+}
+
+// CollectMetrics exposes the up/down state and the number of connections of the server.
+func (ts *TcpServer) CollectMetrics(w *metrics.Writer) {
+	labels := []metrics.Label{
+		{Name: "tcpserver", Value: ts.GetName()},
+		{Name: "address", Value: ts.GetAddress()},
+	}
+
+	ts.mu.Lock()
+	conns := len(ts.conns)
+	ts.mu.Unlock()
+
+	w.Gauge("kermoo_tcpserver_up", "Whether the tcp server is listening (1) or not (0).", metrics.BoolToFloat(ts.IsListening()), labels...)
+	w.Gauge("kermoo_tcpserver_connections", "Number of open connections of the tcp server.", float64(conns), labels...)
+}
+
+// limitedWriter writes up to the given number of bytes and fails afterwards.
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if lw.remaining <= 0 {
+		return 0, errLimitReached
+	}
+
+	truncated := int64(len(p)) > lw.remaining
+	if truncated {
+		p = p[:lw.remaining]
+	}
+
+	n, err := lw.w.Write(p)
+	lw.remaining -= int64(n)
+
+	if err == nil && truncated {
+		err = errLimitReached
+	}
+
+	return n, err
+}
@@ -0,0 +1,120 @@
+package udp_server
+
+import (
+	"fmt"
+	"kermoo/modules/fluent"
+	"math/rand"
+	"time"
+)
+
+const (
+	UDP_FAULT_DROP      = "drop"
+	UDP_FAULT_DELAY     = "delay"
+	UDP_FAULT_DUPLICATE = "duplicate"
+	UDP_FAULT_REORDER   = "reorder"
+	UDP_FAULT_TRUNCATE  = "truncate"
+)
+
+type UdpServerFault struct {
+	// PlanRefs is an optional list of plan names. It can used to avoid redundant
+	// re-declearing of plans in large-scale configurations.
+	// PlanRefs overrides Percentage, Interval and Duration fields are overrided in favor
+	// of the one defined in the referenced plan.
+	PlanRefs []string `json:"planRefs"`
+
+	// Percentage determines the percentage of the datagrams to be faulted. 0 means no
+	// faulted datagram at all and 100 means all of the datagrams are faulted in one of the
+	// ways defined in Types.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// percentages are specified, it'll act like a graph of bars and iterate over them.
+	Percentage fluent.FluentFloat `json:"percentage"`
+
+	// Interval decides how long each percentage should last. A value above one second is
+	// recommended but you're free  to use any interval. Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Duration defines the duration of the entire server. Leave it empty for
+	// life-long running or specify one to end the module completely after that and last decision
+	// will be happening for ever.
+	// In fact, Duration/Interval determines the number of cycle, if defined. Default is empty
+	// for unlimited activity.
+	Duration *fluent.FluentDuration `json:"duration"`
+
+	// Types defines how the response of a faulted datagram can be misbehaved. One of them is
+	// picked randomly for each faulted datagram:
+	//
+	// - drop: no response is sent.
+	// - delay: the response is sent after Delay.
+	// - duplicate: the response is sent twice.
+	// - reorder: the response is held back and sent right after the response of the next datagram.
+	// - truncate: only the first TruncateBytes bytes of the response are sent.
+	//
+	// Default is all of them.
+	Types []string `json:"types"`
+
+	// Delay determines how long the responses should be delayed in the delay fault. It's picked
+	// per datagram, so a ranged or an array of durations acts as a uniform distribution of delays.
+	//
+	// Default is one second.
+	Delay *fluent.FluentDuration `json:"delay"`
+
+	// TruncateBytes determines how many bytes of the response should be sent in the truncate fault.
+	//
+	// Default is half of the response.
+	TruncateBytes *int64 `json:"truncateBytes"`
+}
+
+func (uf *UdpServerFault) GetTypes() []string {
+	if len(uf.Types) > 0 {
+		return uf.Types
+	}
+
+	return []string{UDP_FAULT_DROP, UDP_FAULT_DELAY, UDP_FAULT_DUPLICATE, UDP_FAULT_REORDER, UDP_FAULT_TRUNCATE}
+}
+
+// GetDelay returns a delay for a single datagram.
+func (uf *UdpServerFault) GetDelay() time.Duration {
+	if uf.Delay != nil {
+		return uf.Delay.Get()
+	}
+
+	return time.Second
+}
+
+// GetTruncatedLength returns the number of bytes to be sent out of a response with the
+// given length.
+func (uf *UdpServerFault) GetTruncatedLength(length int) int {
+	if uf.TruncateBytes == nil {
+		return length / 2
+	}
+
+	if *uf.TruncateBytes < int64(length) {
+		return int(*uf.TruncateBytes)
+	}
+
+	return length
+}
+
+// PickType picks one of the fault types randomly.
+func (uf *UdpServerFault) PickType() string {
+	types := uf.GetTypes()
+
+	return types[rand.Intn(len(types))]
+}
+
+func (uf *UdpServerFault) Validate() error {
+	for _, t := range uf.Types {
+		switch t {
+		case UDP_FAULT_DROP, UDP_FAULT_DELAY, UDP_FAULT_DUPLICATE, UDP_FAULT_REORDER, UDP_FAULT_TRUNCATE:
+		default:
+			return fmt.Errorf("fault type %s is not supported", t)
+		}
+	}
+
+	if uf.TruncateBytes != nil && *uf.TruncateBytes < 0 {
+		return fmt.Errorf("truncate bytes can not be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,347 @@
+package udp_server
+
+import (
+	"errors"
+	"fmt"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"kermoo/modules/process"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gosimple/slug"
+	"go.uber.org/zap"
+)
+
+// maxDatagramSize is the largest payload of a UDP datagram.
+const maxDatagramSize = 65535
+
+var _ planner.Plannable = &UdpServer{}
+var _ metrics.Collector = &UdpServer{}
+
+var faultsCounter = metrics.NewCounterVec(
+	"kermoo_udpserver_faults_total",
+	"Number of datagrams which are faulted by the udp servers.",
+	"udpserver", "fault",
+)
+
+type datagram struct {
+	payload []byte
+	addr    net.Addr
+}
+
+type UdpServer struct {
+	planner.CanAssignPlan
+
+	// Interface defines the network interface which the server should listen on. Default
+	// is 0.0.0.0 but you're free to define another one like 127.0.0.1.
+	Interface *string `json:"interface"`
+
+	// Port defines the port which the server should listen on. It's required.
+	Port *int32 `json:"port"`
+
+	// Fault specifies how the server should misbehave. Default is no failure, so that
+	// all of the datagrams are echoed back.
+	Fault *UdpServerFault `json:"fault"`
+
+	conn        net.PacketConn
+	reading     chan struct{}
+	done        chan struct{}
+	percentages []float64
+	held        *datagram
+	received    atomic.Int64
+	isListening atomic.Bool
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+}
+
+func (us *UdpServer) GetName() string {
+	return slug.Make(fmt.Sprintf("udpserver-%s-%d", us.GetInterface(), us.GetPort()))
+}
+
+func (us *UdpServer) GetPort() int32 {
+	if us.Port != nil {
+		return *us.Port
+	}
+
+	return 0
+}
+
+func (us *UdpServer) GetInterface() string {
+	if us.Interface != nil {
+		return *us.Interface
+	}
+
+	return "0.0.0.0"
+}
+
+func (us *UdpServer) GetAddress() string {
+	return fmt.Sprintf("%s:%d", us.GetInterface(), us.GetPort())
+}
+
+func (us *UdpServer) Validate() error {
+	if us.GetPort() <= 0 || us.GetPort() > 65535 {
+		return fmt.Errorf("port is required and must be between 1 and 65535")
+	}
+
+	if us.Fault != nil {
+		if err := us.Fault.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsListening reports whether the server is up and receiving datagrams.
+func (us *UdpServer) IsListening() bool {
+	return us.isListening.Load()
+}
+
+// SetPercentages sets the percentages of the datagrams to be faulted. A datagram is
+// faulted when any of them decides so.
+func (us *UdpServer) SetPercentages(percentages ...float64) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	us.percentages = percentages
+}
+
+// Listen starts receiving and answering the datagrams on the background.
+func (us *UdpServer) Listen() error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	if us.conn != nil {
+		return nil
+	}
+
+	conn, err := net.ListenPacket("udp", us.GetAddress())
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Info("listening udp server...", zap.String("udpserver", us.GetName()))
+
+	us.conn = conn
+	us.reading = make(chan struct{})
+	us.done = make(chan struct{})
+	us.isListening.Store(true)
+
+	go us.read(conn, us.reading)
+
+	return nil
+}
+
+// Stop stops receiving datagrams and drops the delayed and held back responses.
+func (us *UdpServer) Stop() {
+	us.mu.Lock()
+	conn := us.conn
+	reading := us.reading
+	done := us.done
+	us.conn = nil
+	us.mu.Unlock()
+
+	if conn == nil {
+		return
+	}
+
+	logger.Log.Info("udp server stops listening", zap.String("udpserver", us.GetName()))
+
+	conn.Close()
+	<-reading
+
+	close(done)
+	us.wg.Wait()
+
+	us.mu.Lock()
+	us.held = nil
+	us.mu.Unlock()
+
+	us.isListening.Store(false)
+}
+
+func (us *UdpServer) read(conn net.PacketConn, reading chan struct{}) {
+	defer close(reading)
+
+	buf := make([]byte, maxDatagramSize)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logger.Log.Error("failed on reading udp datagram", zap.String("udpserver", us.GetName()), zap.Error(err))
+			}
+			return
+		}
+
+		// A hung application leaves the datagrams unanswered
+		if process.IsHung() {
+			continue
+		}
+
+		us.received.Add(1)
+
+		payload := make([]byte, n)
+		copy(payload, buf[:n])
+
+		us.handle(conn, datagram{payload: payload, addr: addr})
+	}
+}
+
+func (us *UdpServer) handle(conn net.PacketConn, d datagram) {
+	fault := us.pickFault()
+
+	if fault != "" {
+		faultsCounter.Inc(us.GetName(), fault)
+	}
+
+	switch fault {
+	case UDP_FAULT_DROP:
+	case UDP_FAULT_DELAY:
+		delay := us.Fault.GetDelay()
+		done := us.done
+
+		us.wg.Add(1)
+		go func() {
+			defer us.wg.Done()
+
+			select {
+			case <-time.After(delay):
+				us.respond(conn, d)
+			case <-done:
+			}
+		}()
+	case UDP_FAULT_DUPLICATE:
+		us.send(conn, d)
+		us.respond(conn, d)
+	case UDP_FAULT_REORDER:
+		us.mu.Lock()
+		previous := us.held
+		us.held = &d
+		us.mu.Unlock()
+
+		// The previously held back response goes out ahead of this one instead
+		if previous != nil {
+			us.send(conn, *previous)
+		}
+	case UDP_FAULT_TRUNCATE:
+		d.payload = d.payload[:us.Fault.GetTruncatedLength(len(d.payload))]
+		us.respond(conn, d)
+	default:
+		us.respond(conn, d)
+	}
+}
+
+// pickFault decides whether the upcoming datagram should be faulted and picks the type
+// of the fault. An empty string is returned for the healthy datagrams.
+func (us *UdpServer) pickFault() string {
+	if us.Fault == nil {
+		return ""
+	}
+
+	us.mu.Lock()
+	defer us.mu.Unlock()
+
+	for _, percentage := range us.percentages {
+		if rand.Float64()*100 < percentage {
+			return us.Fault.PickType()
+		}
+	}
+
+	return ""
+}
+
+// respond sends the response along with the one which is held back to be reordered.
+func (us *UdpServer) respond(conn net.PacketConn, d datagram) {
+	us.send(conn, d)
+
+	us.mu.Lock()
+	held := us.held
+	us.held = nil
+	us.mu.Unlock()
+
+	if held != nil {
+		us.send(conn, *held)
+	}
+}
+
+func (us *UdpServer) send(conn net.PacketConn, d datagram) {
+	if _, err := conn.WriteTo(d.payload, d.addr); err != nil && !errors.Is(err, net.ErrClosed) {
+		logger.Log.Warn("failed on sending udp datagram", zap.String("udpserver", us.GetName()), zap.Error(err))
+	}
+}
+
+func (us *UdpServer) HasInlinePlan() bool {
+	return us.MakeInlinePlan() != nil
+}
+
+func (us *UdpServer) MakeInlinePlan() *planner.Plan {
+	if us.Fault == nil {
+		return nil
+	}
+
+	plan := planner.NewPlan(planner.Plan{
+		Percentage: &us.Fault.Percentage,
+		Interval:   us.Fault.Interval,
+		Duration:   us.Fault.Duration,
+	})
+
+	return &plan
+}
+
+// Create a lifetime-long plan to serve udp server
+func (us *UdpServer) MakeDefaultPlan() *planner.Plan {
+	plan := planner.NewPlan(planner.Plan{})
+
+	// Value of 0.0 indicates that the server will never fail.
+	plan.Percentage = fluent.NewMustFluentFloat("0.0")
+
+	return &plan
+}
+
+func (us *UdpServer) GetDesiredPlanNames() []string {
+	if us.Fault == nil {
+		return nil
+	}
+
+	return us.Fault.PlanRefs
+}
+
+func (us *UdpServer) GetPlanCycleHooks() planner.CycleHooks {
+	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		percentages := []float64{}
+
+		for _, plan := range us.GetAssignedPlans() {
+			percentages = append(percentages, plan.GetCurrentValue().Percentage)
+		}
+
+		us.SetPercentages(percentages...)
+
+		if err := us.Listen(); err != nil {
+			logger.Log.Error("error while listening to udp server", zap.String("udpserver", us.GetName()), zap.Error(err))
+		}
+
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	return planner.CycleHooks{
+		PreSleep: &preSleep,
+	}
+}
+
+// CollectMetrics exposes the up/down state and the number of received datagrams of the server.
+func (us *UdpServer) CollectMetrics(w *metrics.Writer) {
+	labels := []metrics.Label{
+		{Name: "udpserver", Value: us.GetName()},
+		{Name: "address", Value: us.GetAddress()},
+	}
+
+	w.Gauge("kermoo_udpserver_up", "Whether the udp server is listening (1) or not (0).", metrics.BoolToFloat(us.IsListening()), labels...)
+	w.Counter("kermoo_udpserver_datagrams_total", "Number of datagrams received by the udp server.", float64(us.received.Load()), labels...)
+}
@@ -20,6 +20,9 @@ func MustLoadPreparedConfig(config string) {
 		logger.Log.Panic(err.Error())
 	}
 
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
 	Prepared = *prepared
 }
 
@@ -89,35 +92,28 @@ func getResolvedConfig(config string) (string, error) {
 		return readStdin()
 	}
 
-	// TODO: Change the following to something more sophisticated
-	if !strings.ContainsAny(config, "\n\t{}") {
+	if isConfigPath(config) {
 		return readFile(config)
 	}
 
 	return config, nil
 }
 
+// isConfigPath determines whether the given config is a path to the config file rather
+// than the content of it.
+func isConfigPath(config string) bool {
+	// TODO: Change the following to something more sophisticated
+	return config != "" && config != "-" && !strings.ContainsAny(config, "\n\t{}")
+}
+
 func getAutoloadedConfig() (string, error) {
-	u, err := user.Current()
+	path, err := getAutoloadedConfigPath()
 	if err != nil {
-		return "", fmt.Errorf("unable to determine current user to autoload config: %v", err)
-	}
-
-	pathes := []string{
-		u.HomeDir + "/.kermoo/config.yaml",
-		u.HomeDir + "/.kermoo/config.yml",
-		u.HomeDir + "/.kermoo/config.json",
-		u.HomeDir + "/.config/kermoo/config.yaml",
-		u.HomeDir + "/.config/kermoo/config.yml",
-		u.HomeDir + "/.config/kermoo/config.json",
+		return "", err
 	}
 
-	for _, path := range pathes {
-		content, err := readFile(path)
-
-		if err == nil {
-			return content, nil
-		}
+	if path != "" {
+		return readFile(path)
 	}
 
 	content := os.Getenv("KERMOO_CONFIG")
@@ -125,7 +121,35 @@ func getAutoloadedConfig() (string, error) {
 		return content, nil
 	}
 
-	return "", fmt.Errorf("no config is specified so we tried to autoload config but was unable to load it either from default home paths (like %v) or from the environment variable. kermoo can not live without a config :(", pathes[0])
+	return "", fmt.Errorf("no config is specified so we tried to autoload config but was unable to load it either from default home paths (like %v) or from the environment variable. kermoo can not live without a config :(", getAutoloadPathes("~")[0])
+}
+
+// getAutoloadedConfigPath returns the first existing config file among the default home
+// paths or empty when none of them exists.
+func getAutoloadedConfigPath() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine current user to autoload config: %v", err)
+	}
+
+	for _, path := range getAutoloadPathes(u.HomeDir) {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", nil
+}
+
+func getAutoloadPathes(homeDir string) []string {
+	return []string{
+		homeDir + "/.kermoo/config.yaml",
+		homeDir + "/.kermoo/config.yml",
+		homeDir + "/.kermoo/config.json",
+		homeDir + "/.config/kermoo/config.yaml",
+		homeDir + "/.config/kermoo/config.yml",
+		homeDir + "/.config/kermoo/config.json",
+	}
 }
 
 func readFile(filename string) (string, error) {
@@ -2,11 +2,19 @@ package user_config
 
 import (
 	"fmt"
+	"kermoo/modules/admin"
 	"kermoo/modules/cpu"
+	"kermoo/modules/disk"
+	"kermoo/modules/fd"
+	"kermoo/modules/goroutine"
+	"kermoo/modules/grpc_server"
 	"kermoo/modules/logger"
 	"kermoo/modules/memory"
+	"kermoo/modules/metrics"
 	"kermoo/modules/planner"
 	"kermoo/modules/process"
+	"kermoo/modules/tcp_server"
+	"kermoo/modules/udp_server"
 	"kermoo/modules/utils"
 	"kermoo/modules/web_server"
 	"strings"
@@ -15,6 +23,8 @@ import (
 	"go.uber.org/zap"
 )
 
+// Prepared is the running config. It's only replaced and read while reloadMutex is held,
+// once the reloader is started.
 var Prepared PreparedConfigType
 
 type PreparedConfigType struct {
@@ -22,11 +32,28 @@ type PreparedConfigType struct {
 	Process       *process.Process
 	CpuLoad       *cpu.CpuLoader
 	MemoryLeak    *memory.MemoryLeak
+	DiskFill      *disk.DiskFill
+	DiskIO        *disk.DiskIO
+	FdLeak        *fd.FdLeak
+	GoroutineLeak *goroutine.GoroutineLeak
 	Plans         []*planner.Plan
 	WebServers    []*web_server.WebServer
+	TcpServers    []*tcp_server.TcpServer
+	UdpServers    []*udp_server.UdpServer
+	GrpcServers   []*grpc_server.GrpcServer
+	Admin         *admin.Admin
 }
 
 func (pc *PreparedConfigType) Start() {
+	if pc.Admin != nil {
+		pc.Admin.SetPlans(pc.Plans)
+		pc.Admin.SetCollectors(pc.getMetricsCollectors())
+
+		if err := pc.Admin.ListenOnBackground(); err != nil {
+			logger.Log.Error("error while listening to admin server", zap.Error(err))
+		}
+	}
+
 	if pc.Process != nil && pc.Process.Delay != nil {
 		dur := pc.Process.Delay.Get()
 		logger.Log.Info("sleeping because of process manager configuration...", zap.Duration("sleep", dur))
@@ -34,11 +61,87 @@ func (pc *PreparedConfigType) Start() {
 		logger.Log.Info("woke up.")
 	}
 
+	if pc.Process != nil && pc.Process.Startup != nil {
+		pc.Process.Startup.Begin()
+	}
+
 	for _, plan := range pc.Plans {
 		go plan.Start()
 	}
 }
 
+// getMetricsCollectors returns the modules whose metrics should be exposed.
+func (pc *PreparedConfigType) getMetricsCollectors() []metrics.Collector {
+	collectors := []metrics.Collector{}
+
+	for _, plan := range pc.Plans {
+		collectors = append(collectors, plan)
+	}
+
+	if pc.Process != nil && pc.Process.Hang != nil {
+		collectors = append(collectors, pc.Process.Hang)
+	}
+
+	if pc.CpuLoad != nil {
+		collectors = append(collectors, pc.CpuLoad)
+	}
+
+	if pc.MemoryLeak != nil {
+		collectors = append(collectors, pc.MemoryLeak)
+	}
+
+	if pc.DiskFill != nil {
+		collectors = append(collectors, pc.DiskFill)
+	}
+
+	if pc.DiskIO != nil {
+		collectors = append(collectors, pc.DiskIO)
+	}
+
+	if pc.FdLeak != nil {
+		collectors = append(collectors, pc.FdLeak)
+	}
+
+	if pc.GoroutineLeak != nil {
+		collectors = append(collectors, pc.GoroutineLeak)
+	}
+
+	for _, ws := range pc.WebServers {
+		collectors = append(collectors, ws)
+	}
+
+	for _, ts := range pc.TcpServers {
+		collectors = append(collectors, ts)
+	}
+
+	for _, us := range pc.UdpServers {
+		collectors = append(collectors, us)
+	}
+
+	for _, gs := range pc.GrpcServers {
+		collectors = append(collectors, gs)
+	}
+
+	return collectors
+}
+
+// bindRouteDependencies resolves the components which the routes of the web servers depend on.
+func (pc *PreparedConfigType) bindRouteDependencies() error {
+	for _, ws := range pc.WebServers {
+		for _, route := range ws.Routes {
+			if route.DependsOn == nil {
+				continue
+			}
+
+			if err := route.DependsOn.Bind(pc.MemoryLeak, pc.CpuLoad, pc.WebServers, pc.TcpServers); err != nil {
+				return fmt.Errorf("route %s of webserver %s %v", route.GetName(), ws.GetName(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (u *PreparedConfigType) preparePlannable(plannable planner.Plannable) error {
 	desiredPlans := plannable.GetDesiredPlanNames()
 
@@ -151,6 +254,46 @@ func (pc *PreparedConfigType) validateMemory() error {
 	return nil
 }
 
+func (pc *PreparedConfigType) validateDisk() error {
+	if pc.DiskFill != nil {
+		if err := pc.DiskFill.Validate(); err != nil {
+			return fmt.Errorf("disk filler is invalid: %v", err)
+		}
+	}
+
+	if pc.DiskIO != nil {
+		if err := pc.DiskIO.Validate(); err != nil {
+			return fmt.Errorf("disk i/o is invalid: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (pc *PreparedConfigType) validateFdLeak() error {
+	if pc.FdLeak == nil {
+		return nil
+	}
+
+	if err := pc.FdLeak.Validate(); err != nil {
+		return fmt.Errorf("file descriptor leaker is invalid: %v", err)
+	}
+
+	return nil
+}
+
+func (pc *PreparedConfigType) validateGoroutineLeak() error {
+	if pc.GoroutineLeak == nil {
+		return nil
+	}
+
+	if err := pc.GoroutineLeak.Validate(); err != nil {
+		return fmt.Errorf("goroutine leaker is invalid: %v", err)
+	}
+
+	return nil
+}
+
 func (pc *PreparedConfigType) validateWebservers() error {
 	for _, webServer := range pc.WebServers {
 		err := webServer.Validate()
@@ -170,6 +313,84 @@ func (pc *PreparedConfigType) validateWebservers() error {
 	return nil
 }
 
+func (pc *PreparedConfigType) validateTcpServers() error {
+	for _, tcpServer := range pc.TcpServers {
+		if err := tcpServer.Validate(); err != nil {
+			return fmt.Errorf("tcp server %s is invalid: %v", tcpServer.GetName(), err)
+		}
+
+		for _, webServer := range pc.WebServers {
+			if webServer.GetPort() == tcpServer.GetPort() {
+				return fmt.Errorf("tcp server %s can not listen on the port of webserver %s", tcpServer.GetName(), webServer.GetName())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (pc *PreparedConfigType) validateUdpServers() error {
+	for _, udpServer := range pc.UdpServers {
+		if err := udpServer.Validate(); err != nil {
+			return fmt.Errorf("udp server %s is invalid: %v", udpServer.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+func (pc *PreparedConfigType) validateGrpcServers() error {
+	for _, grpcServer := range pc.GrpcServers {
+		if err := grpcServer.Validate(); err != nil {
+			return fmt.Errorf("grpc server %s is invalid: %v", grpcServer.GetName(), err)
+		}
+
+		for _, webServer := range pc.WebServers {
+			if webServer.GetPort() == grpcServer.GetPort() {
+				return fmt.Errorf("grpc server %s can not listen on the port of webserver %s", grpcServer.GetName(), webServer.GetName())
+			}
+		}
+
+		for _, tcpServer := range pc.TcpServers {
+			if tcpServer.GetPort() == grpcServer.GetPort() {
+				return fmt.Errorf("grpc server %s can not listen on the port of tcp server %s", grpcServer.GetName(), tcpServer.GetName())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (pc *PreparedConfigType) validateAdmin() error {
+	if pc.Admin == nil {
+		return nil
+	}
+
+	if err := pc.Admin.Validate(); err != nil {
+		return fmt.Errorf("admin server is invalid: %v", err)
+	}
+
+	for _, webServer := range pc.WebServers {
+		if webServer.GetPort() == pc.Admin.GetPort() {
+			return fmt.Errorf("admin server can not listen on the port of webserver %s", webServer.GetName())
+		}
+	}
+
+	for _, tcpServer := range pc.TcpServers {
+		if tcpServer.GetPort() == pc.Admin.GetPort() {
+			return fmt.Errorf("admin server can not listen on the port of tcp server %s", tcpServer.GetName())
+		}
+	}
+
+	for _, grpcServer := range pc.GrpcServers {
+		if grpcServer.GetPort() == pc.Admin.GetPort() {
+			return fmt.Errorf("admin server can not listen on the port of grpc server %s", grpcServer.GetName())
+		}
+	}
+
+	return nil
+}
+
 func (pc *PreparedConfigType) Validate() error {
 	if err := pc.validateDuplicateApps(); err != nil {
 		return err
@@ -195,10 +416,38 @@ func (pc *PreparedConfigType) Validate() error {
 		return err
 	}
 
+	if err := pc.validateDisk(); err != nil {
+		return err
+	}
+
+	if err := pc.validateFdLeak(); err != nil {
+		return err
+	}
+
+	if err := pc.validateGoroutineLeak(); err != nil {
+		return err
+	}
+
 	if err := pc.validateWebservers(); err != nil {
 		return err
 	}
 
+	if err := pc.validateTcpServers(); err != nil {
+		return err
+	}
+
+	if err := pc.validateUdpServers(); err != nil {
+		return err
+	}
+
+	if err := pc.validateGrpcServers(); err != nil {
+		return err
+	}
+
+	if err := pc.validateAdmin(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -211,6 +460,18 @@ func (u *PreparedConfigType) findDuplicateApps() []string {
 		apps = append(apps, v.GetName())
 	}
 
+	for _, v := range u.TcpServers {
+		apps = append(apps, v.GetName())
+	}
+
+	for _, v := range u.UdpServers {
+		apps = append(apps, v.GetName())
+	}
+
+	for _, v := range u.GrpcServers {
+		apps = append(apps, v.GetName())
+	}
+
 	return utils.GetDuplicates(apps)
 }
 
@@ -0,0 +1,503 @@
+package user_config
+
+import (
+	"encoding/json"
+	"fmt"
+	"kermoo/modules/grpc_server"
+	"kermoo/modules/logger"
+	"kermoo/modules/planner"
+	"kermoo/modules/process"
+	"kermoo/modules/tcp_server"
+	"kermoo/modules/udp_server"
+	"kermoo/modules/web_server"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// component is a unit of the prepared config which is kept, restarted or removed as a
+// whole during a reload.
+type component struct {
+	name        string
+	fingerprint string
+	plannables  []planner.Plannable
+	stop        func()
+
+	// drain stops the component gracefully within the given grace period on shutdown.
+	// Components with no drain are stopped using stop.
+	drain func(gracePeriod time.Duration)
+}
+
+// Reload applies the next config on the running one. Components which are not changed
+// (including the plans they depend on) keep running untouched. Changed and removed ones
+// are stopped and the changed and added ones are started. The running config is replaced
+// in place, so reloading the global Prepared must be done while reloadMutex is held.
+func (pc *PreparedConfigType) Reload(next *PreparedConfigType) error {
+	if err := next.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	currentComponents, err := pc.getComponents()
+	if err != nil {
+		return err
+	}
+
+	nextComponents, err := next.getComponents()
+	if err != nil {
+		return err
+	}
+
+	kept := map[string]*component{}
+	owners := map[planner.Plannable]string{}
+	stopped := map[*planner.Plan]bool{}
+
+	for _, nc := range nextComponents {
+		for _, plannable := range nc.plannables {
+			owners[plannable] = nc.name
+		}
+	}
+
+	// Stop changed and removed components
+	for _, cc := range currentComponents {
+		nc := findComponent(nextComponents, cc.name)
+
+		if nc != nil && nc.fingerprint == cc.fingerprint {
+			kept[cc.name] = cc
+			continue
+		}
+
+		logger.Log.Info("stopping component due to reload", zap.String("component", cc.name))
+		pc.stopComponent(cc, stopped)
+	}
+
+	// Stop plans which are removed, changed or no longer used
+	currentPlans := []*planner.Plan{}
+	for _, plan := range pc.Plans {
+		nextPlan := next.findPlan(*plan.Name)
+
+		if stopped[plan] {
+			continue
+		}
+
+		if nextPlan == nil || !isSamePlan(plan, nextPlan) {
+			logger.Log.Info("stopping plan due to reload", zap.String("plan", *plan.Name))
+			plan.Stop()
+			continue
+		}
+
+		currentPlans = append(currentPlans, plan)
+	}
+
+	merged := PreparedConfigType{
+		SchemaVersion: next.SchemaVersion,
+		Process:       next.Process,
+		CpuLoad:       next.CpuLoad,
+		MemoryLeak:    next.MemoryLeak,
+		DiskFill:      next.DiskFill,
+		DiskIO:        next.DiskIO,
+		FdLeak:        next.FdLeak,
+		GoroutineLeak: next.GoroutineLeak,
+		WebServers:    next.WebServers,
+		TcpServers:    next.TcpServers,
+		UdpServers:    next.UdpServers,
+		GrpcServers:   next.GrpcServers,
+		Admin:         next.Admin,
+	}
+
+	if kept[pc.Process.GetName()] != nil {
+		merged.Process = pc.Process
+	}
+
+	if kept[pc.CpuLoad.GetName()] != nil {
+		merged.CpuLoad = pc.CpuLoad
+	}
+
+	if kept[pc.MemoryLeak.GetName()] != nil {
+		merged.MemoryLeak = pc.MemoryLeak
+	}
+
+	if kept[pc.DiskFill.GetName()] != nil {
+		merged.DiskFill = pc.DiskFill
+	}
+
+	if kept[pc.DiskIO.GetName()] != nil {
+		merged.DiskIO = pc.DiskIO
+	}
+
+	if kept[pc.FdLeak.GetName()] != nil {
+		merged.FdLeak = pc.FdLeak
+	}
+
+	if kept[pc.GoroutineLeak.GetName()] != nil {
+		merged.GoroutineLeak = pc.GoroutineLeak
+	}
+
+	for i, ws := range next.WebServers {
+		if kept[ws.GetName()] != nil {
+			merged.WebServers[i] = pc.findWebServer(ws.GetName())
+		}
+	}
+
+	for i, ts := range next.TcpServers {
+		if kept[ts.GetName()] != nil {
+			merged.TcpServers[i] = pc.findTcpServer(ts.GetName())
+		}
+	}
+
+	for i, us := range next.UdpServers {
+		if kept[us.GetName()] != nil {
+			merged.UdpServers[i] = pc.findUdpServer(us.GetName())
+		}
+	}
+
+	for i, gs := range next.GrpcServers {
+		if kept[gs.GetName()] != nil {
+			merged.GrpcServers[i] = pc.findGrpcServer(gs.GetName())
+		}
+	}
+
+	// Move the plannables of the upcoming components to the plans which are kept running
+	// and start the new plans.
+	newPlans := []*planner.Plan{}
+	lateAssignments := map[planner.Plannable]*planner.Plan{}
+	for _, nextPlan := range next.Plans {
+		var plan *planner.Plan
+
+		for _, cp := range currentPlans {
+			if *cp.Name == *nextPlan.Name {
+				plan = cp
+			}
+		}
+
+		for _, plannable := range nextPlan.GetPlannables() {
+			if kept[owners[plannable]] != nil {
+				nextPlan.Unassign(plannable)
+			} else if plan != nil {
+				nextPlan.Unassign(plannable)
+				plan.Assign(plannable)
+
+				// A finished plan won't run the hooks of its new plannables anymore
+				if plan.GetState() == planner.PLAN_STATE_FINISHED {
+					lateAssignments[plannable] = plan
+				}
+			}
+		}
+
+		if plan == nil {
+			plan = nextPlan
+			newPlans = append(newPlans, plan)
+		}
+
+		merged.Plans = append(merged.Plans, plan)
+	}
+
+	// Kept routes may depend on the components which are replaced
+	if err := merged.bindRouteDependencies(); err != nil {
+		logger.Log.Error("error while binding route dependencies", zap.Error(err))
+	}
+
+	pc.reloadAdmin(&merged)
+
+	*pc = merged
+
+	for _, plan := range newPlans {
+		logger.Log.Info("starting plan due to reload", zap.String("plan", *plan.Name))
+		go plan.Start()
+	}
+
+	for plannable, plan := range lateAssignments {
+		logger.Log.Info("applying finished plan due to reload", zap.String("plan", *plan.Name), zap.String("plannable", plannable.GetName()))
+		plan.ReplayLastCycle(plannable)
+	}
+
+	return nil
+}
+
+func (pc *PreparedConfigType) reloadAdmin(merged *PreparedConfigType) {
+	if pc.Admin != nil && merged.Admin != nil && fingerprint(pc.Admin) == fingerprint(merged.Admin) {
+		merged.Admin = pc.Admin
+		merged.Admin.SetPlans(merged.Plans)
+		merged.Admin.SetCollectors(merged.getMetricsCollectors())
+		return
+	}
+
+	if pc.Admin != nil {
+		if err := pc.Admin.Stop(); err != nil {
+			logger.Log.Error("error while stopping admin server", zap.Error(err))
+		}
+	}
+
+	if merged.Admin != nil {
+		merged.Admin.SetPlans(merged.Plans)
+		merged.Admin.SetCollectors(merged.getMetricsCollectors())
+
+		if err := merged.Admin.ListenOnBackground(); err != nil {
+			logger.Log.Error("error while listening to admin server", zap.Error(err))
+		}
+	}
+}
+
+// stopComponent detaches the component from its plans, stops the plans which are left
+// with no plannable and then stops the component itself.
+func (pc *PreparedConfigType) stopComponent(c *component, stopped map[*planner.Plan]bool) {
+	for _, plannable := range c.plannables {
+		for _, plan := range pc.findPlansOf(plannable) {
+			plan.Unassign(plannable)
+
+			if len(plan.GetPlannables()) == 0 {
+				plan.Stop()
+				stopped[plan] = true
+			}
+		}
+	}
+
+	if c.stop != nil {
+		c.stop()
+	}
+}
+
+func (pc *PreparedConfigType) getComponents() ([]*component, error) {
+	components := []*component{}
+
+	if pc.Process != nil && (pc.Process.Exit != nil || pc.Process.Crash != nil || pc.Process.Hang != nil) {
+		plannables := []planner.Plannable{}
+
+		if pc.Process.Exit != nil {
+			plannables = append(plannables, pc.Process)
+		}
+
+		if pc.Process.Crash != nil {
+			plannables = append(plannables, pc.Process.Crash)
+		}
+
+		if pc.Process.Hang != nil {
+			plannables = append(plannables, pc.Process.Hang)
+		}
+
+		components = append(components, &component{
+			name:       pc.Process.GetName(),
+			plannables: plannables,
+			stop:       process.Release,
+		})
+	}
+
+	if pc.CpuLoad != nil {
+		components = append(components, &component{
+			name:       pc.CpuLoad.GetName(),
+			plannables: []planner.Plannable{pc.CpuLoad},
+			stop:       pc.CpuLoad.Stop,
+		})
+	}
+
+	if pc.MemoryLeak != nil {
+		components = append(components, &component{
+			name:       pc.MemoryLeak.GetName(),
+			plannables: []planner.Plannable{pc.MemoryLeak},
+			stop:       pc.MemoryLeak.StopLeaking,
+		})
+	}
+
+	if pc.DiskFill != nil {
+		components = append(components, &component{
+			name:       pc.DiskFill.GetName(),
+			plannables: []planner.Plannable{pc.DiskFill},
+			stop:       pc.DiskFill.Stop,
+		})
+	}
+
+	if pc.DiskIO != nil {
+		components = append(components, &component{
+			name:       pc.DiskIO.GetName(),
+			plannables: []planner.Plannable{pc.DiskIO},
+			stop:       pc.DiskIO.Stop,
+		})
+	}
+
+	if pc.FdLeak != nil {
+		components = append(components, &component{
+			name:       pc.FdLeak.GetName(),
+			plannables: []planner.Plannable{pc.FdLeak},
+			stop:       pc.FdLeak.Stop,
+		})
+	}
+
+	if pc.GoroutineLeak != nil {
+		components = append(components, &component{
+			name:       pc.GoroutineLeak.GetName(),
+			plannables: []planner.Plannable{pc.GoroutineLeak},
+			stop:       pc.GoroutineLeak.Stop,
+		})
+	}
+
+	for _, ws := range pc.WebServers {
+		ws := ws
+		plannables := []planner.Plannable{ws}
+
+		for _, route := range ws.Routes {
+			plannables = append(plannables, route)
+
+			if route.Limit != nil {
+				plannables = append(plannables, route.Limit)
+			}
+		}
+
+		if ws.Limit != nil {
+			plannables = append(plannables, ws.Limit)
+		}
+
+		if ws.Tls != nil {
+			plannables = append(plannables, ws.Tls)
+		}
+
+		components = append(components, &component{
+			name:       ws.GetName(),
+			plannables: plannables,
+			stop: func() {
+				if err := ws.Stop(); err != nil {
+					logger.Log.Error("error while stopping webserver", zap.Error(err))
+				}
+			},
+			drain: func(gracePeriod time.Duration) {
+				if err := ws.Drain(gracePeriod); err != nil {
+					logger.Log.Error("error while draining webserver", zap.Error(err))
+				}
+			},
+		})
+	}
+
+	for _, ts := range pc.TcpServers {
+		components = append(components, &component{
+			name:       ts.GetName(),
+			plannables: []planner.Plannable{ts},
+			stop:       ts.Stop,
+		})
+	}
+
+	for _, us := range pc.UdpServers {
+		components = append(components, &component{
+			name:       us.GetName(),
+			plannables: []planner.Plannable{us},
+			stop:       us.Stop,
+		})
+	}
+
+	for _, gs := range pc.GrpcServers {
+		components = append(components, &component{
+			name:       gs.GetName(),
+			plannables: []planner.Plannable{gs},
+			stop:       gs.Stop,
+			drain:      gs.Drain,
+		})
+	}
+
+	for _, c := range components {
+		fp, err := pc.makeFingerprint(c)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compare %s: %v", c.name, err)
+		}
+
+		c.fingerprint = fp
+	}
+
+	return components, nil
+}
+
+// makeFingerprint makes a comparable representation of the component along with the plans
+// it depends on.
+func (pc *PreparedConfigType) makeFingerprint(c *component) (string, error) {
+	parts := []string{}
+
+	for _, plannable := range c.plannables {
+		content, err := json.Marshal(plannable)
+		if err != nil {
+			return "", err
+		}
+
+		plans := []string{}
+		for _, plan := range pc.findPlansOf(plannable) {
+			plans = append(plans, fingerprint(plan))
+		}
+		sort.Strings(plans)
+
+		parts = append(parts, string(content)+strings.Join(plans, ""))
+	}
+
+	return strings.Join(parts, "\n"), nil
+}
+
+func (pc *PreparedConfigType) findPlansOf(plannable planner.Plannable) []*planner.Plan {
+	plans := []*planner.Plan{}
+
+	for _, plan := range pc.Plans {
+		for _, pl := range plan.GetPlannables() {
+			if pl == plannable {
+				plans = append(plans, plan)
+				break
+			}
+		}
+	}
+
+	return plans
+}
+
+func (pc *PreparedConfigType) findWebServer(name string) *web_server.WebServer {
+	for _, ws := range pc.WebServers {
+		if ws.GetName() == name {
+			return ws
+		}
+	}
+
+	return nil
+}
+
+func (pc *PreparedConfigType) findTcpServer(name string) *tcp_server.TcpServer {
+	for _, ts := range pc.TcpServers {
+		if ts.GetName() == name {
+			return ts
+		}
+	}
+
+	return nil
+}
+
+func (pc *PreparedConfigType) findUdpServer(name string) *udp_server.UdpServer {
+	for _, us := range pc.UdpServers {
+		if us.GetName() == name {
+			return us
+		}
+	}
+
+	return nil
+}
+
+func (pc *PreparedConfigType) findGrpcServer(name string) *grpc_server.GrpcServer {
+	for _, gs := range pc.GrpcServers {
+		if gs.GetName() == name {
+			return gs
+		}
+	}
+
+	return nil
+}
+
+func findComponent(components []*component, name string) *component {
+	for _, c := range components {
+		if c.name == name {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func isSamePlan(a *planner.Plan, b *planner.Plan) bool {
+	return fingerprint(a) == fingerprint(b)
+}
+
+func fingerprint(v any) string {
+	content, _ := json.Marshal(v)
+
+	return string(content)
+}
@@ -0,0 +1,110 @@
+package user_config
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"kermoo/modules/logger"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var reloadMutex sync.Mutex
+
+// ReloadPreparedConfig re-parses the given config and applies it on the running prepared
+// config. The running config is left untouched when the given config is invalid.
+func ReloadPreparedConfig(config string) error {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	next, err := MakePreparedConfig(config)
+	if err != nil {
+		return err
+	}
+
+	return Prepared.Reload(next)
+}
+
+// StartReloader reloads the prepared config whenever SIGHUP signal is received.
+func StartReloader(config string) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			logger.Log.Info("reloading config due to SIGHUP signal...")
+			reload(config)
+		}
+	}()
+}
+
+// StartFileWatcher reloads the prepared config whenever the content of the config file
+// changes. Changes are checked on the given interval.
+func StartFileWatcher(config string, interval time.Duration) {
+	path := getConfigPath(config)
+
+	if path == "" || interval <= 0 {
+		return
+	}
+
+	logger.Log.Debug("watching config file for changes...", zap.String("filename", path), zap.Duration("interval", interval))
+
+	go func() {
+		lastChecksum, _ := getFileChecksum(path)
+
+		for {
+			time.Sleep(interval)
+
+			checksum, err := getFileChecksum(path)
+			if err != nil || checksum == lastChecksum {
+				continue
+			}
+
+			lastChecksum = checksum
+
+			logger.Log.Info("reloading config due to file change...", zap.String("filename", path))
+			reload(path)
+		}
+	}()
+}
+
+func reload(config string) {
+	if config == "-" {
+		logger.Log.Warn("unable to reload config since it was read from stdin")
+		return
+	}
+
+	if err := ReloadPreparedConfig(config); err != nil {
+		logger.Log.Error("unable to reload config - keeping the current one", zap.Error(err))
+		return
+	}
+
+	logger.Log.Info("config is reloaded")
+}
+
+// getConfigPath determines the path of the config file, if the config is read from a file.
+func getConfigPath(config string) string {
+	if config == "" {
+		path, _ := getAutoloadedConfigPath()
+		return path
+	}
+
+	if isConfigPath(config) {
+		return config
+	}
+
+	return ""
+}
+
+func getFileChecksum(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(content)), nil
+}
@@ -0,0 +1,137 @@
+package user_config
+
+import (
+	"kermoo/modules/logger"
+	"kermoo/modules/process"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// NotifyShutdownSignals starts catching SIGTERM and SIGINT signals. It should be called
+// before starting the prepared config, so that the signals received during the initial
+// delay are handled as configured rather than killing the process.
+func NotifyShutdownSignals() <-chan os.Signal {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+
+	return signals
+}
+
+// WaitForShutdown blocks until a SIGTERM or SIGINT signal is received on the given channel,
+// then shuts the prepared config down and returns the code which the process should exit
+// with. Another signal during the shutdown makes it return right away.
+func WaitForShutdown(signals <-chan os.Signal) int {
+	alive := time.NewTicker(1 * time.Minute)
+	defer alive.Stop()
+
+	var done chan int
+	var shutdown *process.ProcessShutdown
+
+	for {
+		select {
+		case <-alive.C:
+			process.WaitWhileHung()
+			logger.Log.Info("app is alive")
+		case code := <-done:
+			return code
+		case sig := <-signals:
+			// A hung application doesn't get to handle the signals
+			process.WaitWhileHung()
+
+			if done != nil {
+				if sig == syscall.SIGTERM && shutdown.IgnoreSigterm {
+					continue
+				}
+
+				logger.Log.Warn("exiting right away due to another signal", zap.String("signal", sig.String()))
+				return int(shutdown.ExitCode)
+			}
+
+			// Prepared config might be replaced by reloads until the shutdown is started
+			reloadMutex.Lock()
+			shutdown = Prepared.getShutdown()
+
+			if sig == syscall.SIGTERM && shutdown.IgnoreSigterm {
+				reloadMutex.Unlock()
+				logger.Log.Warn("ignoring SIGTERM signal as configured")
+				continue
+			}
+
+			logger.Log.Info("shutting down due to signal...", zap.String("signal", sig.String()))
+
+			done = make(chan int, 1)
+
+			go func() {
+				// The mutex is kept locked, so no more reloads from now on
+				done <- Prepared.Shutdown()
+			}()
+		}
+	}
+}
+
+// Shutdown drains the web servers within the grace period and stops the rest of the
+// components, so that they can clean up after themselves. It returns the code which the
+// process should exit with.
+func (pc *PreparedConfigType) Shutdown() int {
+	shutdown := pc.getShutdown()
+
+	if keepServing := shutdown.GetKeepServing(); keepServing > 0 {
+		logger.Log.Info("keep serving before shutting down...", zap.Duration("duration", keepServing))
+		time.Sleep(keepServing)
+	}
+
+	for _, plan := range pc.Plans {
+		plan.Stop()
+	}
+
+	components, err := pc.getComponents()
+	if err != nil {
+		logger.Log.Error("unable to stop components", zap.Error(err))
+	}
+
+	var wg sync.WaitGroup
+
+	for _, c := range components {
+		wg.Add(1)
+
+		go func(c *component) {
+			defer wg.Done()
+
+			if c.drain != nil {
+				c.drain(shutdown.GetGracePeriod())
+			} else if c.stop != nil {
+				c.stop()
+			}
+		}(c)
+	}
+
+	wg.Wait()
+
+	if pc.Admin != nil {
+		if err := pc.Admin.Stop(); err != nil {
+			logger.Log.Error("error while stopping admin server", zap.Error(err))
+		}
+	}
+
+	if exitDelay := shutdown.GetExitDelay(); exitDelay > 0 {
+		logger.Log.Info("delaying the exit...", zap.Duration("duration", exitDelay))
+		time.Sleep(exitDelay)
+	}
+
+	logger.Log.Info("shut down", zap.Int("exit_code", int(shutdown.ExitCode)))
+
+	return int(shutdown.ExitCode)
+}
+
+func (pc *PreparedConfigType) getShutdown() *process.ProcessShutdown {
+	if pc.Process != nil && pc.Process.Shutdown != nil {
+		return pc.Process.Shutdown
+	}
+
+	return &process.ProcessShutdown{}
+}
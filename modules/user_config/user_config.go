@@ -2,10 +2,17 @@ package user_config
 
 import (
 	"fmt"
+	"kermoo/modules/admin"
 	"kermoo/modules/cpu"
+	"kermoo/modules/disk"
+	"kermoo/modules/fd"
+	"kermoo/modules/goroutine"
+	"kermoo/modules/grpc_server"
 	"kermoo/modules/memory"
 	"kermoo/modules/planner"
 	"kermoo/modules/process"
+	"kermoo/modules/tcp_server"
+	"kermoo/modules/udp_server"
 	"kermoo/modules/web_server"
 )
 
@@ -32,6 +39,31 @@ type UserConfigType struct {
 	// By default, no memory leak is simulated.
 	MemoryLeak *memory.MemoryLeak
 
+	// DiskFill optionally simulates the disk pressure by writing a file up to the given size
+	// into a directory. The file is removed once the module ends.
+	//
+	// By default, no disk fill is simulated.
+	DiskFill *disk.DiskFill `json:"diskFill"`
+
+	// DiskIO optionally simulates a sustained disk read and/or write throughput along with
+	// fsync storms in a directory.
+	//
+	// By default, no disk I/O is simulated.
+	DiskIO *disk.DiskIO `json:"diskIO"`
+
+	// FdLeak optionally simulates the exhaustion of file descriptors by leaking opened files,
+	// pipes or idle TCP connections. You can specify interval, duration and count of the leak.
+	//
+	// By default, no file descriptor leak is simulated.
+	FdLeak *fd.FdLeak `json:"fdLeak"`
+
+	// GoroutineLeak optionally simulates the leak of goroutines - and OS threads, if they're
+	// locked to them - by spawning blocked goroutines on each cycle. You can specify interval,
+	// duration and rate of the leak.
+	//
+	// By default, no goroutine leak is simulated.
+	GoroutineLeak *goroutine.GoroutineLeak `json:"goroutineLeak"`
+
 	// WebServers is an optional array of web servers that will be used to serve defined routes.
 	// It can be configured to fail with percentage over an specific duration of time with specific
 	// interval. Routes can be configured too.
@@ -39,9 +71,38 @@ type UserConfigType struct {
 	// By default, no web server is initiated.
 	WebServers []*web_server.WebServer `json:"webServers"`
 
+	// TcpServers is an optional array of raw TCP servers which echo or send a banner to the
+	// connections. They can be configured to refuse, reset, hang or early-close the connections
+	// with percentage over an specific duration of time with specific interval.
+	//
+	// By default, no tcp server is initiated.
+	TcpServers []*tcp_server.TcpServer `json:"tcpServers"`
+
+	// UdpServers is an optional array of UDP servers which echo the datagrams back. They can be
+	// configured to drop, delay, duplicate, reorder or truncate a percentage of the responses
+	// over an specific duration of time with specific interval.
+	//
+	// By default, no udp server is initiated.
+	UdpServers []*udp_server.UdpServer `json:"udpServers"`
+
+	// GrpcServers is an optional array of gRPC servers which serve the standard health-check
+	// protocol along with an echo service. They can be configured to fail the calls with
+	// UNAVAILABLE, DEADLINE_EXCEEDED or RESOURCE_EXHAUSTED, or to report NOT_SERVING, with
+	// percentage over an specific duration of time with specific interval.
+	//
+	// By default, no grpc server is initiated.
+	GrpcServers []*grpc_server.GrpcServer `json:"grpcServers"`
+
 	// Plans is an optional array of plans which is there to avoid re-defining some repeatitive
 	// failure plans. It can be refered from a webServer, route, cpuLoad, or memoryLeak.
 	Plans []*planner.Plan `json:"plans"`
+
+	// Admin optionally enables an admin HTTP server on a separate port which exposes endpoints
+	// to inspect plans and steer them at runtime - such as pausing, resuming, overriding their
+	// values or skipping to their next sub-plan.
+	//
+	// By default, no admin server is initiated.
+	Admin *admin.Admin `json:"admin"`
 }
 
 func (u *UserConfigType) Validate() error {
@@ -61,20 +122,33 @@ func (u *UserConfigType) GetPreparedConfig() (*PreparedConfigType, error) {
 	if u.Process != nil {
 		prepared.Process = u.Process
 
-		if u.Process.Exit != nil {
-			if err := u.Process.Validate(); err != nil {
-				return nil, fmt.Errorf("invalid process manager: %v", err)
-			}
+		if err := u.Process.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid process manager: %v", err)
+		}
 
+		if u.Process.Exit != nil {
 			if err := prepared.preparePlannable(u.Process); err != nil {
 				return nil, fmt.Errorf("unable to prepare process manager: %v", err)
 			}
 		}
 
+		if u.Process.Crash != nil {
+			if err := prepared.preparePlannable(u.Process.Crash); err != nil {
+				return nil, fmt.Errorf("unable to prepare process crash: %v", err)
+			}
+		}
+
+		if u.Process.Hang != nil {
+			if err := prepared.preparePlannable(u.Process.Hang); err != nil {
+				return nil, fmt.Errorf("unable to prepare process hang: %v", err)
+			}
+		}
 	}
 
 	// Prepare CPU Load
 	if u.CpuLoad != nil {
+		prepared.CpuLoad = u.CpuLoad
+
 		if err := u.CpuLoad.Validate(); err != nil {
 			return nil, fmt.Errorf("invalid cpu load: %v", err)
 		}
@@ -86,6 +160,8 @@ func (u *UserConfigType) GetPreparedConfig() (*PreparedConfigType, error) {
 
 	// Prepare Memory Leaker
 	if u.MemoryLeak != nil {
+		prepared.MemoryLeak = u.MemoryLeak
+
 		if err := u.MemoryLeak.Validate(); err != nil {
 			return nil, fmt.Errorf("invalid memory leaker: %v", err)
 		}
@@ -95,11 +171,91 @@ func (u *UserConfigType) GetPreparedConfig() (*PreparedConfigType, error) {
 		}
 	}
 
+	// Prepare Disk Filler
+	if u.DiskFill != nil {
+		prepared.DiskFill = u.DiskFill
+
+		if err := u.DiskFill.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid disk filler: %v", err)
+		}
+
+		if err := prepared.preparePlannable(u.DiskFill); err != nil {
+			return nil, fmt.Errorf("unable to prepare disk filler: %v", err)
+		}
+	}
+
+	// Prepare Disk I/O
+	if u.DiskIO != nil {
+		prepared.DiskIO = u.DiskIO
+
+		if err := u.DiskIO.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid disk i/o: %v", err)
+		}
+
+		if err := prepared.preparePlannable(u.DiskIO); err != nil {
+			return nil, fmt.Errorf("unable to prepare disk i/o: %v", err)
+		}
+	}
+
+	// Prepare File Descriptor Leaker
+	if u.FdLeak != nil {
+		prepared.FdLeak = u.FdLeak
+
+		if err := u.FdLeak.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid file descriptor leaker: %v", err)
+		}
+
+		if err := prepared.preparePlannable(u.FdLeak); err != nil {
+			return nil, fmt.Errorf("unable to prepare file descriptor leaker: %v", err)
+		}
+	}
+
+	// Prepare Goroutine Leaker
+	if u.GoroutineLeak != nil {
+		prepared.GoroutineLeak = u.GoroutineLeak
+
+		if err := u.GoroutineLeak.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid goroutine leaker: %v", err)
+		}
+
+		if err := prepared.preparePlannable(u.GoroutineLeak); err != nil {
+			return nil, fmt.Errorf("unable to prepare goroutine leaker: %v", err)
+		}
+	}
+
 	// Prepare Web Server
 	if err := u.prepareWebservers(&prepared); err != nil {
 		return nil, err
 	}
 
+	// Prepare TCP Server
+	if err := u.prepareTcpServers(&prepared); err != nil {
+		return nil, err
+	}
+
+	// Prepare UDP Server
+	if err := u.prepareUdpServers(&prepared); err != nil {
+		return nil, err
+	}
+
+	// Prepare gRPC Server
+	if err := u.prepareGrpcServers(&prepared); err != nil {
+		return nil, err
+	}
+
+	if err := prepared.bindRouteDependencies(); err != nil {
+		return nil, err
+	}
+
+	// Prepare Admin Server
+	if u.Admin != nil {
+		prepared.Admin = u.Admin
+
+		if err := prepared.validateAdmin(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &prepared, nil
 }
 
@@ -125,6 +281,72 @@ func (u *UserConfigType) prepareWebservers(p *PreparedConfigType) error {
 			if err := p.preparePlannable(route); err != nil {
 				return fmt.Errorf("unable to prepare route %s webserver %s: %v", route.GetName(), ws.GetName(), err)
 			}
+
+			if route.Limit != nil && route.Limit.IsPlannable() {
+				if err := p.preparePlannable(route.Limit); err != nil {
+					return fmt.Errorf("unable to prepare limit of route %s webserver %s: %v", route.GetName(), ws.GetName(), err)
+				}
+			}
+		}
+
+		if ws.Limit != nil && ws.Limit.IsPlannable() {
+			if err := p.preparePlannable(ws.Limit); err != nil {
+				return fmt.Errorf("unable to prepare limit of webserver %s: %v", ws.GetName(), err)
+			}
+		}
+
+		if ws.Tls != nil {
+			if err := p.preparePlannable(ws.Tls); err != nil {
+				return fmt.Errorf("unable to prepare tls of webserver %s: %v", ws.GetName(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (u *UserConfigType) prepareTcpServers(p *PreparedConfigType) error {
+	for _, ts := range u.TcpServers {
+		if err := ts.Validate(); err != nil {
+			return fmt.Errorf("invalid tcp server %s: %v", ts.GetName(), err)
+		}
+
+		p.TcpServers = append(p.TcpServers, ts)
+
+		if err := p.preparePlannable(ts); err != nil {
+			return fmt.Errorf("unable to prepare tcp server %s: %v", ts.GetName(), err)
+		}
+	}
+
+	return p.validateTcpServers()
+}
+
+func (u *UserConfigType) prepareGrpcServers(p *PreparedConfigType) error {
+	for _, gs := range u.GrpcServers {
+		if err := gs.Validate(); err != nil {
+			return fmt.Errorf("invalid grpc server %s: %v", gs.GetName(), err)
+		}
+
+		p.GrpcServers = append(p.GrpcServers, gs)
+
+		if err := p.preparePlannable(gs); err != nil {
+			return fmt.Errorf("unable to prepare grpc server %s: %v", gs.GetName(), err)
+		}
+	}
+
+	return p.validateGrpcServers()
+}
+
+func (u *UserConfigType) prepareUdpServers(p *PreparedConfigType) error {
+	for _, us := range u.UdpServers {
+		if err := us.Validate(); err != nil {
+			return fmt.Errorf("invalid udp server %s: %v", us.GetName(), err)
+		}
+
+		p.UdpServers = append(p.UdpServers, us)
+
+		if err := p.preparePlannable(us); err != nil {
+			return fmt.Errorf("unable to prepare udp server %s: %v", us.GetName(), err)
 		}
 	}
 
@@ -1,9 +1,14 @@
 package utils
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"net"
+	"os"
+	"runtime/pprof"
+	"strconv"
+	"strings"
 	"time"
 
 	"math/rand"
@@ -172,3 +177,24 @@ func GetMemoryUsage() (uint64, error) {
 
 	return uint64(vmem.Used), nil
 }
+
+// GetThreadCount returns the number of OS threads of the process. Where the procfs is
+// not available, it falls back to the number of threads created by the Go runtime.
+func GetThreadCount() int {
+	file, err := os.Open("/proc/self/status")
+	if err != nil {
+		return pprof.Lookup("threadcreate").Count()
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if value, found := strings.CutPrefix(scanner.Text(), "Threads:"); found {
+			if count, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+				return count
+			}
+		}
+	}
+
+	return pprof.Lookup("threadcreate").Count()
+}
@@ -0,0 +1,294 @@
+package web_server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"kermoo/modules/fluent"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+const (
+	FAULT_KIND_STATUS  = "status"
+	FAULT_KIND_RESET   = "reset"
+	FAULT_KIND_HANG    = "hang"
+	FAULT_KIND_PARTIAL = "partial"
+	FAULT_KIND_DRIP    = "drip"
+)
+
+// FaultResponse is a response which can be served by a failing route.
+type FaultResponse struct {
+	// Kind determines how the response is served:
+	//
+	// - status: a regular response with the given status, body and headers.
+	// - reset: closes the connection without any response.
+	// - hang: sends the headers and never sends the body.
+	// - partial: sends the headers with the Content-Length of the whole body, but closes the
+	//   connection after sending half of it.
+	// - drip: sends the body one byte per DripInterval.
+	//
+	// Default is status.
+	Kind string `json:"kind"`
+
+	// Status defines the HTTP status code of the response, such as 503 or 429.
+	//
+	// It's required for the status kind and defaults to 200 for the hang, partial and drip
+	// kinds, so that they look healthy at first.
+	Status int `json:"status"`
+
+	// Weight determines how often the response is picked relative to the other ones. For
+	// example, weights of 70 and 30 make the first response to be served 70% of the time.
+	//
+	// Default is 1.
+	Weight *float64 `json:"weight"`
+
+	// Body defines the response body as a Go text/template. The template can make use of
+	// .Status, .StatusText, .Method, .Path and .Time of the request, e.g.
+	// `{"error": "{{ .StatusText }}", "path": "{{ .Path }}"}`.
+	//
+	// Default is the status text, such as "Service Unavailable".
+	Body string `json:"body"`
+
+	// Headers defines extra headers of the response, such as Retry-After or Content-Type.
+	//
+	// Default is no extra headers.
+	Headers map[string]string `json:"headers"`
+
+	// DripInterval determines how long to wait before sending each byte of the body in the
+	// drip kind.
+	//
+	// Default is one second.
+	DripInterval *fluent.FluentDuration `json:"dripInterval"`
+
+	template *template.Template
+}
+
+// FaultResponseData is the data which the templates of fault responses are rendered with.
+type FaultResponseData struct {
+	Status     int
+	StatusText string
+	Method     string
+	Path       string
+	Time       time.Time
+}
+
+func (fr *FaultResponse) GetKind() string {
+	if fr.Kind != "" {
+		return fr.Kind
+	}
+
+	return FAULT_KIND_STATUS
+}
+
+func (fr *FaultResponse) GetStatus() int {
+	if fr.Status == 0 && fr.GetKind() != FAULT_KIND_STATUS {
+		return http.StatusOK
+	}
+
+	return fr.Status
+}
+
+func (fr *FaultResponse) GetWeight() float64 {
+	if fr.Weight != nil {
+		return *fr.Weight
+	}
+
+	return 1
+}
+
+func (fr *FaultResponse) GetDripInterval() time.Duration {
+	if fr.DripInterval != nil {
+		return fr.DripInterval.Get()
+	}
+
+	return time.Second
+}
+
+// GetLabel returns a short representation of the response to be used in the metrics, which
+// is the status code for the status kind and the kind itself for the others.
+func (fr *FaultResponse) GetLabel() string {
+	if fr.GetKind() == FAULT_KIND_STATUS {
+		return strconv.Itoa(fr.GetStatus())
+	}
+
+	return fr.GetKind()
+}
+
+func (fr *FaultResponse) Validate() error {
+	switch fr.GetKind() {
+	case FAULT_KIND_STATUS, FAULT_KIND_RESET, FAULT_KIND_HANG, FAULT_KIND_PARTIAL, FAULT_KIND_DRIP:
+	default:
+		return fmt.Errorf("fault kind %s is not supported", fr.Kind)
+	}
+
+	if fr.GetStatus() < 100 || fr.GetStatus() > 599 {
+		return fmt.Errorf("status %d is not a valid HTTP status code", fr.Status)
+	}
+
+	if fr.GetWeight() <= 0 {
+		return fmt.Errorf("weight of status %d must be greater than zero", fr.Status)
+	}
+
+	tmpl, err := fr.parseTemplate()
+	if err != nil {
+		return fmt.Errorf("body of status %d is not a valid template: %v", fr.Status, err)
+	}
+
+	// Catch the templates which parse but fail on rendering, such as unknown fields
+	if err := tmpl.Execute(io.Discard, FaultResponseData{}); err != nil {
+		return fmt.Errorf("body of status %d is not a valid template: %v", fr.Status, err)
+	}
+
+	fr.template = tmpl
+
+	return nil
+}
+
+func (fr *FaultResponse) parseTemplate() (*template.Template, error) {
+	return template.New(fmt.Sprintf("fault-%d", fr.Status)).Parse(fr.Body)
+}
+
+// Write writes the response for the given request. An error is only returned when the body
+// can not be rendered, before anything is written.
+func (fr *FaultResponse) Write(w http.ResponseWriter, r *http.Request) error {
+	if fr.GetKind() == FAULT_KIND_RESET {
+		reset(w)
+		return nil
+	}
+
+	body, err := fr.render(r)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range fr.Headers {
+		w.Header().Set(name, value)
+	}
+
+	switch fr.GetKind() {
+	case FAULT_KIND_HANG:
+		w.WriteHeader(fr.GetStatus())
+		flush(w)
+
+		// Hang until the client gives up or the server is shut down
+		<-r.Context().Done()
+		return nil
+	case FAULT_KIND_PARTIAL:
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(fr.GetStatus())
+
+		if _, err := w.Write(body[:len(body)/2]); err != nil {
+			// The client has given up
+			return nil
+		}
+
+		flush(w)
+
+		// Abort the response, so the connection is closed before sending the whole body
+		panic(http.ErrAbortHandler)
+	case FAULT_KIND_DRIP:
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(fr.GetStatus())
+		flush(w)
+
+		for i := range body {
+			select {
+			case <-time.After(fr.GetDripInterval()):
+			case <-r.Context().Done():
+				return nil
+			}
+
+			if _, err := w.Write(body[i : i+1]); err != nil {
+				// The client has given up
+				return nil
+			}
+
+			flush(w)
+		}
+
+		return nil
+	default:
+		w.WriteHeader(fr.GetStatus())
+
+		// An error means the client has given up
+		_, _ = w.Write(body)
+
+		return nil
+	}
+}
+
+func (fr *FaultResponse) render(r *http.Request) ([]byte, error) {
+	if fr.Body == "" {
+		return []byte(http.StatusText(fr.GetStatus())), nil
+	}
+
+	tmpl := fr.template
+	if tmpl == nil {
+		// The response is not validated beforehand
+		var err error
+		if tmpl, err = fr.parseTemplate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+
+	err := tmpl.Execute(&buf, FaultResponseData{
+		Status:     fr.GetStatus(),
+		StatusText: http.StatusText(fr.GetStatus()),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Time:       time.Now(),
+	})
+
+	return buf.Bytes(), err
+}
+
+// reset closes the connection without any response. On HTTP/1.x, the connection is reset
+// (RST) while on HTTP/2, where the connection can not be taken over, the stream is reset.
+func reset(w http.ResponseWriter) {
+	if conn, _, err := http.NewResponseController(w).Hijack(); err == nil {
+		closeWithReset(conn)
+		return
+	}
+
+	panic(http.ErrAbortHandler)
+}
+
+func closeWithReset(conn net.Conn) {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		// Discard the unsent data and send RST on close
+		_ = tcpConn.SetLinger(0)
+	}
+
+	conn.Close()
+}
+
+func flush(w http.ResponseWriter) {
+	_ = http.NewResponseController(w).Flush()
+}
+
+// pickFaultResponse picks one of the responses randomly with respect to their weights.
+func pickFaultResponse(responses []*FaultResponse) *FaultResponse {
+	total := 0.0
+	for _, response := range responses {
+		total += response.GetWeight()
+	}
+
+	pick := rand.Float64() * total
+
+	for _, response := range responses {
+		pick -= response.GetWeight()
+
+		if pick < 0 {
+			return response
+		}
+	}
+
+	return responses[len(responses)-1]
+}
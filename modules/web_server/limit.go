@@ -0,0 +1,245 @@
+package web_server
+
+import (
+	"fmt"
+	"kermoo/modules/fluent"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	LIMIT_REASON_RATE        = "rate"
+	LIMIT_REASON_CONCURRENCY = "concurrency"
+)
+
+var _ planner.Plannable = &Limit{}
+
+var limitRejectionsCounter = metrics.NewCounterVec(
+	"kermoo_limit_rejections_total",
+	"Number of requests which are rejected by the rate and concurrency limits.",
+	"limit", "reason",
+)
+
+// Limit rejects the requests which exceed a rate or a number of concurrent requests.
+type Limit struct {
+	planner.CanAssignPlan
+
+	// PlanRefs is an optional list of plan names. It can used to avoid redundant
+	// re-declearing of plans in large-scale configurations.
+	// PlanRefs overrides Rate, Interval and Duration fields are overrided in favor
+	// of the one defined in the referenced plan.
+	// The size of the referenced plan is considered as the rate.
+	PlanRefs []string `json:"planRefs"`
+
+	// Rate determines the number of requests per second which are allowed. The requests
+	// are limited using a token bucket, so short bursts above the rate are allowed up to Burst.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// rates are specified, it'll act like a graph of bars and iterate over them - so that
+	// the capacity can degrade over time.
+	//
+	// Default is unlimited.
+	Rate *fluent.FluentSize `json:"rate"`
+
+	// Interval decides how long each rate should last. Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Duration defines the duration of the entire rate plan. Leave it empty for life-long
+	// running or specify one to end it after that and last rate will be used for ever.
+	// In fact, Duration/Interval determines the number of cycle, if defined. Default is empty
+	// for unlimited activity.
+	Duration *fluent.FluentDuration `json:"duration"`
+
+	// Burst determines the number of requests which can be served at once before being
+	// limited by the rate.
+	//
+	// Default is the rate itself.
+	Burst *int64 `json:"burst"`
+
+	// Concurrency determines the maximum number of in-flight requests.
+	//
+	// Default is unlimited.
+	Concurrency *int64 `json:"concurrency"`
+
+	// Status defines the status code of the rejected requests.
+	//
+	// Default is 429 for exceeding the rate and 503 for exceeding the concurrency.
+	Status *int `json:"status"`
+
+	owner    string
+	tokens   float64
+	last     time.Time
+	inFlight atomic.Int64
+	mu       sync.Mutex
+}
+
+func (l *Limit) GetName() string {
+	return fmt.Sprintf("%s-limit", l.owner)
+}
+
+// IsPlannable determines whether the limit needs a plan to drive its rate.
+func (l *Limit) IsPlannable() bool {
+	return len(l.PlanRefs) > 0 || l.HasInlinePlan()
+}
+
+func (l *Limit) Validate() error {
+	if !l.IsPlannable() && l.Concurrency == nil {
+		return fmt.Errorf("no rate, concurrency or plan refs is set")
+	}
+
+	if len(l.PlanRefs) > 1 {
+		return fmt.Errorf("plan refs can not contain more than one element")
+	}
+
+	if l.Burst != nil && *l.Burst <= 0 {
+		return fmt.Errorf("burst must be greater than zero")
+	}
+
+	if l.Concurrency != nil && *l.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be greater than zero")
+	}
+
+	if l.Status != nil && (*l.Status < 100 || *l.Status > 599) {
+		return fmt.Errorf("status %d is not a valid HTTP status code", *l.Status)
+	}
+
+	if l.HasInlinePlan() {
+		if err := l.MakeInlinePlan().Validate(); err != nil {
+			return fmt.Errorf("crafted plan validation failed: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// GetRate returns the current number of requests per second which are allowed, or zero
+// for unlimited.
+func (l *Limit) GetRate() int64 {
+	for _, plan := range l.GetAssignedPlans() {
+		if cv := plan.GetCurrentValue(); cv != nil {
+			return cv.Size
+		}
+	}
+
+	return 0
+}
+
+func (l *Limit) getBurst(rate int64) float64 {
+	if l.Burst != nil {
+		return float64(*l.Burst)
+	}
+
+	return math.Max(float64(rate), 1)
+}
+
+func (l *Limit) getStatus(reason string) int {
+	if l.Status != nil {
+		return *l.Status
+	}
+
+	if reason == LIMIT_REASON_CONCURRENCY {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusTooManyRequests
+}
+
+// takeToken takes a token out of the bucket. When there's no token, it returns how long
+// it takes for the next one to be available.
+func (l *Limit) takeToken() (bool, time.Duration) {
+	rate := l.GetRate()
+	if rate <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	burst := l.getBurst(rate)
+
+	if l.last.IsZero() {
+		l.tokens = burst
+	} else {
+		l.tokens = math.Min(burst, l.tokens+now.Sub(l.last).Seconds()*float64(rate))
+	}
+
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - l.tokens) / float64(rate) * float64(time.Second))
+}
+
+// Wrap makes a handler which rejects the requests exceeding the limit and passes the
+// others to the given handler.
+func (l *Limit) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := l.takeToken(); !ok {
+			l.reject(w, LIMIT_REASON_RATE, wait)
+			return
+		}
+
+		if l.Concurrency != nil {
+			if l.inFlight.Add(1) > *l.Concurrency {
+				l.inFlight.Add(-1)
+				l.reject(w, LIMIT_REASON_CONCURRENCY, time.Second)
+				return
+			}
+
+			defer l.inFlight.Add(-1)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *Limit) reject(w http.ResponseWriter, reason string, retryAfter time.Duration) {
+	limitRejectionsCounter.Inc(l.GetName(), reason)
+
+	status := l.getStatus(reason)
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(http.StatusText(status)))
+}
+
+func (l *Limit) HasInlinePlan() bool {
+	return l.MakeInlinePlan() != nil
+}
+
+func (l *Limit) MakeInlinePlan() *planner.Plan {
+	if l.Rate == nil {
+		return nil
+	}
+
+	plan := planner.NewPlan(planner.Plan{
+		Size:     l.Rate,
+		Interval: l.Interval,
+		Duration: l.Duration,
+	})
+
+	return &plan
+}
+
+func (l *Limit) MakeDefaultPlan() *planner.Plan {
+	return nil
+}
+
+func (l *Limit) GetDesiredPlanNames() []string {
+	return l.PlanRefs
+}
+
+func (l *Limit) GetPlanCycleHooks() planner.CycleHooks {
+	// The rate is read from the plan on each request
+	return planner.CycleHooks{}
+}
@@ -0,0 +1,221 @@
+package web_server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"kermoo/modules/fluent"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type recordContextKey struct{}
+
+// RequestRecorder records the received requests of a web server in a ring buffer and
+// exposes them as JSON, like a webhook catcher.
+type RequestRecorder struct {
+	// Path defines where the recorded requests are exposed on. A GET request on the path
+	// returns the recorded requests, oldest first, and a POST request on {path}/clear
+	// clears them. Requests to these endpoints are not recorded.
+	//
+	// Default is /kermoo/requests.
+	Path string `json:"path"`
+
+	// Size determines how many of the latest requests are kept.
+	//
+	// Default is 100.
+	Size *int `json:"size"`
+
+	// MaxBodySize determines how many bytes of the request bodies are recorded. The rest of
+	// the bodies are still served but not recorded.
+	//
+	// Default is 64Ki.
+	MaxBodySize *fluent.FluentSize `json:"maxBodySize"`
+
+	records []*RecordedRequest
+	mu      sync.Mutex
+}
+
+// RecordedRequest is a request received by the web server.
+type RecordedRequest struct {
+	Time          time.Time           `json:"time"`
+	ConnectedFrom string              `json:"connected_from"`
+	Method        string              `json:"method"`
+	Host          string              `json:"host"`
+	Path          string              `json:"path"`
+	Query         map[string][]string `json:"query"`
+	Headers       map[string][]string `json:"headers"`
+	Body          string              `json:"body"`
+	BodyTruncated bool                `json:"body_truncated"`
+	Status        int                 `json:"status"`
+	Faulted       bool                `json:"faulted"`
+}
+
+func (rr *RequestRecorder) GetPath() string {
+	if rr.Path != "" {
+		return rr.Path
+	}
+
+	return "/kermoo/requests"
+}
+
+func (rr *RequestRecorder) GetSize() int {
+	if rr.Size != nil {
+		return *rr.Size
+	}
+
+	return 100
+}
+
+func (rr *RequestRecorder) GetMaxBodySize() int64 {
+	if rr.MaxBodySize != nil {
+		return rr.MaxBodySize.Get()
+	}
+
+	return 64 * 1024
+}
+
+func (rr *RequestRecorder) Validate() error {
+	if !strings.HasPrefix(rr.GetPath(), "/") {
+		return fmt.Errorf("path must start with /")
+	}
+
+	if rr.GetSize() <= 0 {
+		return fmt.Errorf("size must be greater than zero")
+	}
+
+	if rr.GetMaxBodySize() < 0 {
+		return fmt.Errorf("max body size can not be negative")
+	}
+
+	return nil
+}
+
+// GetRecords returns the recorded requests, oldest first.
+func (rr *RequestRecorder) GetRecords() []*RecordedRequest {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	records := make([]*RecordedRequest, len(rr.records))
+	copy(records, rr.records)
+
+	return records
+}
+
+// Clear removes all of the recorded requests.
+func (rr *RequestRecorder) Clear() {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.records = nil
+}
+
+func (rr *RequestRecorder) add(record *RecordedRequest) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	rr.records = append(rr.records, record)
+
+	if overflow := len(rr.records) - rr.GetSize(); overflow > 0 {
+		rr.records = rr.records[overflow:]
+	}
+}
+
+// registerRoutes registers the endpoints of the recorded requests.
+func (rr *RequestRecorder) registerRoutes(r *mux.Router) {
+	r.HandleFunc(rr.GetPath(), func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		j := json.NewEncoder(w)
+		j.SetIndent("", "  ")
+
+		if err := j.Encode(rr.GetRecords()); err != nil {
+			panic(err)
+		}
+	}).Methods("GET")
+
+	r.HandleFunc(rr.GetPath()+"/clear", func(w http.ResponseWriter, r *http.Request) {
+		rr.Clear()
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("POST")
+}
+
+// Wrap makes a handler which records the requests passed to the given handler.
+func (rr *RequestRecorder) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == rr.GetPath() || r.URL.Path == rr.GetPath()+"/clear" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		record := &RecordedRequest{
+			Time:          time.Now(),
+			ConnectedFrom: r.RemoteAddr,
+			Method:        r.Method,
+			Host:          r.Host,
+			Path:          r.URL.Path,
+			Query:         r.URL.Query(),
+			Headers:       r.Header.Clone(),
+		}
+
+		// A ranged size is computed once, so the limit is consistent within a request
+		maxBodySize := rr.GetMaxBodySize()
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+		if err == nil {
+			if int64(len(body)) > maxBodySize {
+				record.BodyTruncated = true
+				record.Body = string(body[:maxBodySize])
+			} else {
+				record.Body = string(body)
+			}
+		}
+
+		// Give the handler the whole body, including the part which is read here
+		r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(context.WithValue(r.Context(), recordContextKey{}, record))
+
+		defer func() {
+			record.Status = sw.status
+			rr.add(record)
+		}()
+
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// markFaulted marks the recorded request, if any, as faulted.
+func markFaulted(r *http.Request) {
+	if record, ok := r.Context().Value(recordContextKey{}).(*RecordedRequest); ok {
+		record.Faulted = true
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// statusWriter captures the status code of the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	sw.status = status
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController, so that flushing and
+// hijacking keep working.
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"kermoo/config"
 	"kermoo/modules/fluent"
+	"kermoo/modules/goroutine"
 	"kermoo/modules/planner"
+	"kermoo/modules/process"
 	"kermoo/modules/utils"
 	"net/http"
+	"net/http/httputil"
 	"os"
+	"runtime"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/gosimple/slug"
@@ -36,6 +41,24 @@ type Route struct {
 
 	// Fault defines how the route should fail. Default is no failure.
 	Fault *RouteFault `json:"fault"`
+
+	// Limit defines the rate and concurrency limits of the route. Default is no limit.
+	Limit *Limit `json:"limit"`
+
+	// WaitForStartup makes the route respond with 503 until the initialization of the process
+	// completes, like a readiness or startup probe.
+	//
+	// Default is false, except for the default /readyz and /startupz routes.
+	WaitForStartup bool `json:"waitForStartup"`
+
+	// DependsOn makes the route respond with 503 while other components of Kermoo are under
+	// pressure or down, like a health route which reflects the dependencies of a service.
+	//
+	// Default is not depending on any other component.
+	DependsOn *RouteDependencies `json:"dependsOn"`
+
+	webServerName string
+	hijacked      *hijackedConnections
 }
 
 type RouteContent struct {
@@ -55,6 +78,158 @@ type RouteContent struct {
 	//
 	// Default is disabled.
 	NoServerInfo bool `json:"serverInfo"`
+
+	// Template defines the response body as a Go text/template, which can not be used along
+	// with Static or Whoami. The template can make use of the request as .Method, .Path,
+	// .Params (path params such as {id}), .Query, .Headers and .Body along with .Env,
+	// .Hostname, .Uptime, .Time and the current cycle of the route plan as .Cycle. A json
+	// function is there too, e.g. `{"id": {{ json .Params.id }}, "q": {{ json (.Query.Get "q") }}}`.
+	//
+	// Default is disabled.
+	Template string `json:"template"`
+
+	// File defines the path of a file to be served, along with support of range and
+	// conditional requests.
+	//
+	// Default is disabled.
+	File string `json:"file"`
+
+	// Directory defines the path of a directory whose tree is served under the route path,
+	// e.g. /downloads/a/b.txt serves a/b.txt of the directory on a /downloads route.
+	//
+	// Default is disabled.
+	Directory string `json:"directory"`
+
+	// RandomPayload defines the size of a payload of random bytes to be served. It's picked
+	// per request, so a ranged or an array of sizes acts as a uniform distribution of sizes.
+	//
+	// Default is disabled.
+	RandomPayload *fluent.FluentSize `json:"randomPayload"`
+
+	// Proxy defines an upstream URL, such as http://127.0.0.1:8080, which the requests are
+	// forwarded to. The whole tree under the route path is forwarded with the same path and
+	// the Host header of the requests, while the faults of the route are still applied.
+	//
+	// Default is disabled.
+	Proxy string `json:"proxy"`
+
+	// WebSocket serves a WebSocket endpoint which echoes the messages back and sends pings as
+	// heartbeats.
+	//
+	// Default is disabled.
+	WebSocket *WebSocketContent `json:"websocket"`
+
+	// Sse serves a Server-Sent Events stream which emits an event on each interval and sends
+	// comments as heartbeats.
+	//
+	// Default is disabled.
+	Sse *SseContent `json:"sse"`
+
+	// ContentType defines the Content-Type header of the response. It's not applied on Proxy.
+	//
+	// Default is application/json for Whoami, application/octet-stream for RandomPayload,
+	// detected from the extension for File and Directory and text/html for the others.
+	ContentType string `json:"contentType"`
+
+	// Status defines the status code of the response. It's not applied on File, Directory
+	// and Proxy.
+	//
+	// Default is 200.
+	Status int `json:"status"`
+
+	template *template.Template
+	proxy    *httputil.ReverseProxy
+}
+
+// IsStream determines whether the content is a long-lived stream.
+func (rc *RouteContent) IsStream() bool {
+	return rc.WebSocket != nil || rc.Sse != nil
+}
+
+// IsPrefixed determines whether the content serves the whole tree under the route path.
+func (rc *RouteContent) IsPrefixed() bool {
+	return rc.Directory != "" || rc.Proxy != ""
+}
+
+func (rc *RouteContent) GetContentType() string {
+	if rc.ContentType != "" {
+		return rc.ContentType
+	}
+
+	if rc.Whoami {
+		return "application/json"
+	}
+
+	return "text/html"
+}
+
+func (rc *RouteContent) GetStatus() int {
+	if rc.Status != 0 {
+		return rc.Status
+	}
+
+	return http.StatusOK
+}
+
+func (rc *RouteContent) Validate() error {
+	modes := 0
+	for _, enabled := range []bool{rc.Static != "", rc.Whoami, rc.Template != "", rc.File != "", rc.Directory != "", rc.RandomPayload != nil, rc.Proxy != "", rc.WebSocket != nil, rc.Sse != nil} {
+		if enabled {
+			modes++
+		}
+	}
+
+	if modes > 1 {
+		return fmt.Errorf("only one of static, whoami, template, file, directory, random payload, proxy, websocket and sse can be set")
+	}
+
+	if rc.WebSocket != nil {
+		if err := rc.WebSocket.Validate(); err != nil {
+			return fmt.Errorf("websocket is invalid: %v", err)
+		}
+	}
+
+	if rc.Sse != nil {
+		if err := rc.Sse.Validate(); err != nil {
+			return fmt.Errorf("sse is invalid: %v", err)
+		}
+	}
+
+	if rc.Proxy != "" {
+		proxy, err := makeReverseProxy(rc.Proxy)
+		if err != nil {
+			return fmt.Errorf("proxy is invalid: %v", err)
+		}
+
+		rc.proxy = proxy
+	}
+
+	if rc.File != "" {
+		if err := validatePath(rc.File, false); err != nil {
+			return fmt.Errorf("file is invalid: %v", err)
+		}
+	}
+
+	if rc.Directory != "" {
+		if err := validatePath(rc.Directory, true); err != nil {
+			return fmt.Errorf("directory is invalid: %v", err)
+		}
+	}
+
+	if rc.Status != 0 && (rc.Status < 100 || rc.Status > 599) {
+		return fmt.Errorf("status %d is not a valid HTTP status code", rc.Status)
+	}
+
+	if rc.Template != "" {
+		tmpl, err := parseRouteTemplate(rc.Template)
+		if err != nil {
+			return fmt.Errorf("template is invalid: %v", err)
+		}
+
+		rc.template = tmpl
+	}
+
+	return nil
 }
 
 func (route *Route) GetName() string {
@@ -84,6 +259,11 @@ func (route *Route) MakeInlinePlan() *planner.Plan {
 		Duration:   route.Fault.Duration,
 	})
 
+	// A fault which only delays the responses has no percentage, so it will never fail.
+	if route.Fault.Percentage.GetParsedValue() == nil {
+		plan.Percentage = fluent.NewMustFluentFloat("0.0")
+	}
+
 	return &plan
 }
 
@@ -107,25 +287,100 @@ func (route *Route) GetPlanCycleHooks() planner.CycleHooks {
 	}
 }
 
+// getPlanState determines whether the route should succeed at the moment, along with the
+// latency of its plans.
+func (route *Route) getPlanState() (bool, time.Duration) {
+	shouldSuccess := true
+	planLatency := time.Duration(0)
+
+	for _, plan := range route.GetAssignedPlans() {
+		cv := plan.GetCurrentValue()
+
+		// Plan has not started its first cycle yet
+		if cv == nil {
+			continue
+		}
+
+		if cv.Latency > planLatency {
+			planLatency = cv.Latency
+		}
+
+		if !*cv.ComputedPercentageChance {
+			shouldSuccess = false
+		}
+	}
+
+	return shouldSuccess, planLatency
+}
+
 func (route *Route) Handle(w http.ResponseWriter, r *http.Request) {
+	if route.WaitForStartup && process.IsInitializing() {
+		http.Error(w, "I'm Initializing...", http.StatusServiceUnavailable)
+		return
+	}
+
+	if route.DependsOn != nil {
+		if message := route.DependsOn.getUnhealthyMessage(); message != "" {
+			http.Error(w, message, http.StatusServiceUnavailable)
+			return
+		}
+	}
+
 	if route.Fault != nil {
-		shouldSuccess := true
+		shouldSuccess, planLatency := route.getPlanState()
 
-		for _, plan := range route.GetAssignedPlans() {
-			if !*plan.GetCurrentValue().ComputedPercentageChance {
-				shouldSuccess = false
-				break
-			}
+		// Stream faults are applied in the middle of the streams
+		if route.Fault.Stream != nil {
+			shouldSuccess = true
+		}
+
+		if route.Fault.ShouldDelay(!shouldSuccess) {
+			time.Sleep(route.Fault.GetResponseDelay() + planLatency)
 		}
 
 		if !shouldSuccess {
-			route.Fault.Handle(w, r)
+			markFaulted(r)
+			route.Fault.Handle(w, r, route.webServerName, route.Path)
+			return
+		}
+	}
+
+	switch {
+	case route.Content.File != "":
+		route.serveFile(w, r)
+		return
+	case route.Content.Directory != "":
+		route.serveDirectory(w, r)
+		return
+	case route.Content.RandomPayload != nil:
+		route.servePayload(w, r)
+		return
+	case route.Content.Proxy != "":
+		route.serveProxy(w, r)
+		return
+	case route.Content.WebSocket != nil:
+		route.serveWebSocket(w, r)
+		return
+	case route.Content.Sse != nil:
+		route.serveSse(w, r)
+		return
+	}
+
+	if route.Content.Template != "" {
+		content, err := route.renderTemplate(r)
+
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to render template: %v", err), http.StatusInternalServerError)
 			return
 		}
+
+		route.writeContent(w, content)
+		return
 	}
 
 	if route.Content.Whoami {
-		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Type", route.Content.GetContentType())
+		w.WriteHeader(route.Content.GetStatus())
 		j := json.NewEncoder(w)
 		j.SetIndent("", "  ")
 		err := j.Encode(route.Content.GetReflectionContent(r))
@@ -142,8 +397,13 @@ func (route *Route) Handle(w http.ResponseWriter, r *http.Request) {
 		content = "Hello from Kermoo!"
 	}
 
-	w.Header().Set("Content-Type", "text/html")
-	_, err := w.Write([]byte(content))
+	route.writeContent(w, []byte(content))
+}
+
+func (route *Route) writeContent(w http.ResponseWriter, content []byte) {
+	w.Header().Set("Content-Type", route.Content.GetContentType())
+	w.WriteHeader(route.Content.GetStatus())
+	_, err := w.Write(content)
 
 	if err != nil {
 		panic(err)
@@ -179,15 +439,46 @@ func (route *Route) Validate() error {
 		return err
 	}
 
+	if err := route.Content.Validate(); err != nil {
+		return err
+	}
+
 	if route.Fault != nil {
-		if len(route.Fault.GetBadStatuses()) == 0 {
-			return fmt.Errorf("route has no fault status - client and/or server errors needs to be enabled")
+		if err := route.Fault.Validate(); err != nil {
+			return err
+		}
+
+		if route.Fault.Stream != nil && !route.Content.IsStream() {
+			return fmt.Errorf("stream faults can only be used along with websocket and sse")
+		}
+	}
+
+	if route.Limit != nil {
+		route.Limit.owner = route.GetName()
+
+		if err := route.Limit.Validate(); err != nil {
+			return fmt.Errorf("limit is invalid: %v", err)
+		}
+	}
+
+	if route.DependsOn != nil {
+		if err := route.DependsOn.Validate(); err != nil {
+			return fmt.Errorf("dependencies are invalid: %v", err)
 		}
 	}
 
 	return nil
 }
 
+// GetHandler returns the handler of the route with respect to its limits.
+func (route *Route) GetHandler() http.Handler {
+	if route.Limit != nil {
+		return route.Limit.Wrap(http.HandlerFunc(route.Handle))
+	}
+
+	return http.HandlerFunc(route.Handle)
+}
+
 func (rc *RouteContent) GetReflectionContent(r *http.Request) ReflectorResponse {
 	now := time.Now()
 
@@ -201,6 +492,12 @@ func (rc *RouteContent) GetReflectionContent(r *http.Request) ReflectorResponse
 			UptimeSeconds: int64(now.Sub(config.InitializedAt).Seconds()),
 			InterfaceIps:  utils.GetIpList(),
 			KermooVersion: config.BuildVersion,
+			Runtime: RuntimeInfo{
+				Goroutines:       runtime.NumGoroutine(),
+				Threads:          utils.GetThreadCount(),
+				LeakedGoroutines: goroutine.GetLeakedGoroutines(),
+				LockedThreads:    goroutine.GetLockedThreads(),
+			},
 		}
 	}
 
@@ -223,12 +520,20 @@ type ReflectorResponse struct {
 }
 
 type ServerInfo struct {
-	Hostname      string   `json:"hostname"`
-	InitializedAt string   `json:"initialized_at"`
-	CurrentTime   string   `json:"current_time"`
-	UptimeSeconds int64    `json:"uptime_seconds"`
-	InterfaceIps  []string `json:"interface_ips"`
-	KermooVersion string   `json:"kermoo_version"`
+	Hostname      string      `json:"hostname"`
+	InitializedAt string      `json:"initialized_at"`
+	CurrentTime   string      `json:"current_time"`
+	UptimeSeconds int64       `json:"uptime_seconds"`
+	InterfaceIps  []string    `json:"interface_ips"`
+	KermooVersion string      `json:"kermoo_version"`
+	Runtime       RuntimeInfo `json:"runtime"`
+}
+
+type RuntimeInfo struct {
+	Goroutines       int   `json:"goroutines"`
+	Threads          int   `json:"threads"`
+	LeakedGoroutines int64 `json:"leaked_goroutines"`
+	LockedThreads    int64 `json:"locked_threads"`
 }
 
 type RequestInfo struct {
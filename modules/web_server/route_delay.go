@@ -0,0 +1,116 @@
+package web_server
+
+import (
+	"fmt"
+	"kermoo/modules/fluent"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	DELAY_DISTRIBUTION_NORMAL      = "normal"
+	DELAY_DISTRIBUTION_EXPONENTIAL = "exponential"
+	DELAY_DISTRIBUTION_PARETO      = "pareto"
+)
+
+// z-score of the 99th percentile in a standard normal distribution
+const normalP99ZScore = 2.3263
+
+type DelayDistribution struct {
+	// Type determines the shape of the distribution. It can be one of "normal", "exponential"
+	// or "pareto". Pareto is a long-tail distribution which is a good fit for simulating
+	// realistic tail latencies.
+	Type string `json:"type"`
+
+	// P50 is the targeted median of the delays. It must be a single duration, rather than
+	// a range or an array.
+	P50 fluent.FluentDuration `json:"p50"`
+
+	// P99 is the targeted 99th percentile of the delays. It must be a single duration which
+	// is greater than P50.
+	P99 fluent.FluentDuration `json:"p99"`
+
+	// Max optionally caps the delays so that the tail of the distribution won't produce
+	// unreasonably long delays. It must be a single duration too.
+	//
+	// Default is no cap.
+	Max *fluent.FluentDuration `json:"max"`
+}
+
+func (dd *DelayDistribution) Validate() error {
+	if !dd.isSet(dd.P50) || !dd.isSet(dd.P99) {
+		return fmt.Errorf("both p50 and p99 of the delay distribution are required")
+	}
+
+	// Percentiles are fixed, as re-drawing them on each sample would distort the distribution
+	if !isSingleValue(dd.P50.GetParsedValue()) || !isSingleValue(dd.P99.GetParsedValue()) || (dd.Max != nil && !isSingleValue(dd.Max.GetParsedValue())) {
+		return fmt.Errorf("p50, p99 and max of the delay distribution can not be ranged or arrays")
+	}
+
+	if dd.P50.Get() < 0 {
+		return fmt.Errorf("p50 of the delay distribution can not be negative")
+	}
+
+	if dd.P99.Get() <= dd.P50.Get() {
+		return fmt.Errorf("p99 of the delay distribution must be greater than its p50")
+	}
+
+	if dd.Type == DELAY_DISTRIBUTION_PARETO && dd.P50.Get() == 0 {
+		return fmt.Errorf("p50 of the pareto delay distribution must be greater than zero")
+	}
+
+	switch dd.Type {
+	case DELAY_DISTRIBUTION_NORMAL, DELAY_DISTRIBUTION_EXPONENTIAL, DELAY_DISTRIBUTION_PARETO:
+		return nil
+	}
+
+	return fmt.Errorf("%s is not a valid delay distribution type", dd.Type)
+}
+
+// Sample draws a random delay from the distribution.
+func (dd *DelayDistribution) Sample() time.Duration {
+	p50 := float64(dd.P50.Get())
+	p99 := float64(dd.P99.Get())
+
+	var value float64
+
+	switch dd.Type {
+	case DELAY_DISTRIBUTION_NORMAL:
+		sigma := (p99 - p50) / normalP99ZScore
+		value = p50 + rand.NormFloat64()*sigma
+	case DELAY_DISTRIBUTION_EXPONENTIAL:
+		// A shifted exponential distribution is used so that both of p50 and p99 can be met.
+		rate := (math.Log(100) - math.Log(2)) / (p99 - p50)
+		offset := p50 - math.Log(2)/rate
+		value = offset + rand.ExpFloat64()/rate
+	case DELAY_DISTRIBUTION_PARETO:
+		alpha := math.Log(50) / math.Log(p99/p50)
+		scale := p50 / math.Pow(2, 1/alpha)
+		value = scale * math.Pow(1-rand.Float64(), -1/alpha)
+	}
+
+	if value < 0 {
+		value = 0
+	}
+
+	if dd.Max != nil && value > float64(dd.Max.Get()) {
+		value = float64(dd.Max.Get())
+	}
+
+	if value > math.MaxInt64 {
+		value = math.MaxInt64
+	}
+
+	return time.Duration(value)
+}
+
+func (dd *DelayDistribution) isSet(f fluent.FluentDuration) bool {
+	return f.GetParsedValue() != nil
+}
+
+// isSingleValue determines whether the value is a single one, rather than a range or an
+// array which yields a different value on each call.
+func isSingleValue[T int64 | float64 | time.Duration](pv *fluent.ParsedValue[T]) bool {
+	return pv != nil && !pv.IsRanged() && len(pv.GetValues()) == 1
+}
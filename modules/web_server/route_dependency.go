@@ -0,0 +1,178 @@
+package web_server
+
+import (
+	"fmt"
+	"kermoo/modules/cpu"
+	"kermoo/modules/fluent"
+	"kermoo/modules/memory"
+	"kermoo/modules/tcp_server"
+	"strings"
+	"sync/atomic"
+)
+
+// RouteDependencies makes the route respond with 503 while other components of Kermoo are
+// under pressure or down, so that health routes honestly report the internal state.
+type RouteDependencies struct {
+	// MemoryLeakAbove makes the route unhealthy while the memory leaker leaks more than the
+	// given size, e.g. 500Mi. It must be a single size, rather than a range or an array.
+	//
+	// Default is not depending on the memory leaker.
+	MemoryLeakAbove *fluent.FluentSize `json:"memoryLeakAbove"`
+
+	// CpuLoadAbove makes the route unhealthy while the targeted load of the CPU loader is above
+	// the given percentage. It must be a single percentage, rather than a range or an array.
+	//
+	// Default is not depending on the CPU loader.
+	CpuLoadAbove *fluent.FluentFloat `json:"cpuLoadAbove"`
+
+	// WebServers makes the route unhealthy while any of the named web servers is down, such
+	// as "webserver-0-0-0-0-8080".
+	//
+	// Default is not depending on any web server.
+	WebServers []string `json:"webServers"`
+
+	// TcpServers makes the route unhealthy while any of the named tcp servers is down, such
+	// as "tcpserver-0-0-0-0-5432".
+	//
+	// Default is not depending on any tcp server.
+	TcpServers []string `json:"tcpServers"`
+
+	targets atomic.Pointer[dependencyTargets]
+}
+
+// dependencyTargets holds the components which the dependencies are checked against.
+type dependencyTargets struct {
+	memoryLeak *memory.MemoryLeak
+	cpuLoad    *cpu.CpuLoader
+	webServers []*WebServer
+	tcpServers []*tcp_server.TcpServer
+}
+
+func (rd *RouteDependencies) Validate() error {
+	// Thresholds are fixed, otherwise the route would flap with no change in the components
+	if rd.MemoryLeakAbove != nil && !isSingleValue(rd.MemoryLeakAbove.GetParsedValue()) {
+		return fmt.Errorf("memory leak threshold can not be ranged or an array")
+	}
+
+	if rd.CpuLoadAbove != nil {
+		if !isSingleValue(rd.CpuLoadAbove.GetParsedValue()) {
+			return fmt.Errorf("cpu load threshold can not be ranged or an array")
+		}
+
+		if p := rd.CpuLoadAbove.Get(); p < 0 || p > 100 {
+			return fmt.Errorf("cpu load percentage %v is out of range of 0-100", p)
+		}
+	}
+
+	for _, name := range append(append([]string{}, rd.WebServers...), rd.TcpServers...) {
+		if name == "" {
+			return fmt.Errorf("server names can not be empty")
+		}
+	}
+
+	return nil
+}
+
+// Bind resolves the dependencies among the given components. It fails when a dependency
+// refers to a component which is not configured.
+func (rd *RouteDependencies) Bind(memoryLeak *memory.MemoryLeak, cpuLoad *cpu.CpuLoader, webServers []*WebServer, tcpServers []*tcp_server.TcpServer) error {
+	targets := dependencyTargets{}
+
+	if rd.MemoryLeakAbove != nil {
+		if memoryLeak == nil {
+			return fmt.Errorf("depends on the memory leaker which is not configured")
+		}
+
+		targets.memoryLeak = memoryLeak
+	}
+
+	if rd.CpuLoadAbove != nil {
+		if cpuLoad == nil {
+			return fmt.Errorf("depends on the cpu loader which is not configured")
+		}
+
+		targets.cpuLoad = cpuLoad
+	}
+
+	for _, name := range rd.WebServers {
+		found := false
+
+		for _, ws := range webServers {
+			if ws.GetName() == name {
+				targets.webServers = append(targets.webServers, ws)
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("depends on webserver %s which is not configured", name)
+		}
+	}
+
+	for _, name := range rd.TcpServers {
+		found := false
+
+		for _, ts := range tcpServers {
+			if ts.GetName() == name {
+				targets.tcpServers = append(targets.tcpServers, ts)
+				found = true
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("depends on tcp server %s which is not configured", name)
+		}
+	}
+
+	rd.targets.Store(&targets)
+
+	return nil
+}
+
+// GetUnhealthyReasons returns why the route is unhealthy at the moment. It's empty when all
+// of the dependencies are fine.
+func (rd *RouteDependencies) GetUnhealthyReasons() []string {
+	targets := rd.targets.Load()
+	if targets == nil {
+		return nil
+	}
+
+	reasons := []string{}
+
+	if targets.memoryLeak != nil {
+		if leaked, threshold := targets.memoryLeak.GetLeakedSize(), rd.MemoryLeakAbove.Get(); leaked > threshold {
+			reasons = append(reasons, fmt.Sprintf("memory leak of %d bytes is above %d bytes", leaked, threshold))
+		}
+	}
+
+	if targets.cpuLoad != nil {
+		if load, threshold := targets.cpuLoad.GetTargetPercentage(), rd.CpuLoadAbove.Get(); load > threshold {
+			reasons = append(reasons, fmt.Sprintf("cpu load of %v%% is above %v%%", load, threshold))
+		}
+	}
+
+	for _, ws := range targets.webServers {
+		if !ws.IsListening() {
+			reasons = append(reasons, fmt.Sprintf("webserver %s is down", ws.GetName()))
+		}
+	}
+
+	for _, ts := range targets.tcpServers {
+		if !ts.IsListening() {
+			reasons = append(reasons, fmt.Sprintf("tcp server %s is down", ts.GetName()))
+		}
+	}
+
+	return reasons
+}
+
+// getUnhealthyMessage returns the response body of the route while it's unhealthy, or an
+// empty string when it's healthy.
+func (rd *RouteDependencies) getUnhealthyMessage() string {
+	reasons := rd.GetUnhealthyReasons()
+	if len(reasons) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("I'm Unhealthy: %s", strings.Join(reasons, ", "))
+}
@@ -1,9 +1,17 @@
 package web_server
 
 import (
+	"fmt"
 	"kermoo/modules/fluent"
-	"math/rand"
+	"kermoo/modules/metrics"
 	"net/http"
+	"time"
+)
+
+var routeFaultsCounter = metrics.NewCounterVec(
+	"kermoo_route_faults_total",
+	"Number of faulty responses injected by the routes, by status code or connection-level or stream fault kind.",
+	"webserver", "route", "code",
 )
 
 type RouteFault struct {
@@ -40,7 +48,23 @@ type RouteFault struct {
 	Duration *fluent.FluentDuration `json:"duration"`
 
 	// ResponseDelay adds a delay to each response - no matter if its in good or bad state.
-	ResponseDelay fluent.FluentDuration `json:"responseDelay"`
+	// The delay is picked per request, so a ranged or an array of durations acts as a uniform
+	// distribution of delays.
+	//
+	// Default is no delay.
+	ResponseDelay *fluent.FluentDuration `json:"responseDelay"`
+
+	// ResponseDelayDistribution draws the delay of each response from a statistical distribution
+	// (normal, exponential or pareto) targeting the given p50 and p99. It can not be used
+	// along with ResponseDelay.
+	//
+	// Default is no delay.
+	ResponseDelayDistribution *DelayDistribution `json:"responseDelayDistribution"`
+
+	// DelayFaultsOnly indicates the response delay to be applied only on failed responses.
+	//
+	// Default is false so that all of the responses are delayed.
+	DelayFaultsOnly *bool `json:"delayFaultsOnly"`
 
 	// ClientErrors indicates when the route is in failing state, it can respond with 4xx (client
 	// side errors).
@@ -53,6 +77,19 @@ type RouteFault struct {
 	//
 	// Default is true.
 	ServerErrors *bool `json:"serverErrors"`
+
+	// Responses defines a weighted list of responses to be served when the route is in failing
+	// state, each one with its own status code, body and headers or a connection-level fault
+	// kind such as reset or hang. It can not be used along with ClientErrors and ServerErrors.
+	//
+	// Default is a random 5xx (and 4xx, if client errors are enabled) response.
+	Responses []*FaultResponse `json:"responses"`
+
+	// Stream makes the WebSocket and SSE routes misbehave in the middle of their streams when
+	// the route is in failing state, instead of failing the requests upfront.
+	//
+	// Default is failing the requests upfront.
+	Stream *StreamFault `json:"stream"`
 }
 
 type RouteStatus struct {
@@ -88,14 +125,79 @@ func (RouteFault *RouteFault) GetBadStatuses() []RouteStatus {
 	return statuses
 }
 
-func (RouteFault *RouteFault) Handle(w http.ResponseWriter, r *http.Request) {
-	statuses := RouteFault.GetBadStatuses()
-	randomError := statuses[rand.Intn(len(statuses))]
+// GetResponses returns the responses which the route can fail with.
+func (RouteFault *RouteFault) GetResponses() []*FaultResponse {
+	if len(RouteFault.Responses) > 0 {
+		return RouteFault.Responses
+	}
+
+	responses := []*FaultResponse{}
+
+	for _, status := range RouteFault.GetBadStatuses() {
+		responses = append(responses, &FaultResponse{Status: status.Code, Body: status.Description})
+	}
+
+	return responses
+}
+
+func (RouteFault *RouteFault) Validate() error {
+	if len(RouteFault.Responses) > 0 {
+		if RouteFault.ClientErrors != nil || RouteFault.ServerErrors != nil {
+			return fmt.Errorf("responses can not be used along with client and server errors")
+		}
+
+		for _, response := range RouteFault.Responses {
+			if err := response.Validate(); err != nil {
+				return err
+			}
+		}
+	} else if len(RouteFault.GetBadStatuses()) == 0 {
+		return fmt.Errorf("route has no fault status - client and/or server errors needs to be enabled")
+	}
+
+	if RouteFault.Stream != nil {
+		if err := RouteFault.Stream.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if RouteFault.ResponseDelay != nil && RouteFault.ResponseDelayDistribution != nil {
+		return fmt.Errorf("response delay and response delay distribution can not be used together")
+	}
+
+	if RouteFault.ResponseDelayDistribution != nil {
+		if err := RouteFault.ResponseDelayDistribution.Validate(); err != nil {
+			return fmt.Errorf("invalid response delay distribution: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ShouldDelay determines whether the response should be delayed or not.
+func (RouteFault *RouteFault) ShouldDelay(isFaulty bool) bool {
+	return isFaulty || RouteFault.DelayFaultsOnly == nil || !*RouteFault.DelayFaultsOnly
+}
+
+// GetResponseDelay computes a fresh delay for a response.
+func (RouteFault *RouteFault) GetResponseDelay() time.Duration {
+	if RouteFault.ResponseDelayDistribution != nil {
+		return RouteFault.ResponseDelayDistribution.Sample()
+	}
+
+	if RouteFault.ResponseDelay != nil {
+		return RouteFault.ResponseDelay.Get()
+	}
+
+	return 0
+}
+
+func (RouteFault *RouteFault) Handle(w http.ResponseWriter, r *http.Request, webServer string, route string) {
+	response := pickFaultResponse(RouteFault.GetResponses())
 
-	w.WriteHeader(randomError.Code)
-	_, err := w.Write([]byte(randomError.Description))
+	routeFaultsCounter.Inc(webServer, route, response.GetLabel())
 
-	if err != nil {
-		panic(err)
+	if err := response.Write(w, r); err != nil {
+		http.Error(w, fmt.Sprintf("unable to render fault response: %v", err), http.StatusInternalServerError)
 	}
 }
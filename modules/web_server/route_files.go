@@ -0,0 +1,83 @@
+package web_server
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// payloadChunkSize is the size of the chunks which the random payloads are written with.
+const payloadChunkSize = 32 * 1024
+
+// serveFile serves the file with support of range and conditional requests.
+func (route *Route) serveFile(w http.ResponseWriter, r *http.Request) {
+	file, err := os.Open(route.Content.File)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to open file: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to open file: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if route.Content.ContentType != "" {
+		w.Header().Set("Content-Type", route.Content.ContentType)
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// serveDirectory serves the directory tree under the route path.
+func (route *Route) serveDirectory(w http.ResponseWriter, r *http.Request) {
+	http.StripPrefix(route.Path, http.FileServer(http.Dir(route.Content.Directory))).ServeHTTP(w, r)
+}
+
+// servePayload serves a freshly sized payload of random bytes.
+func (route *Route) servePayload(w http.ResponseWriter, r *http.Request) {
+	size := route.Content.RandomPayload.Get()
+
+	contentType := route.Content.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.WriteHeader(route.Content.GetStatus())
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	source := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// The client might give up in the middle of a large payload, which is fine
+	_, _ = io.CopyBuffer(w, io.LimitReader(source, size), make([]byte, payloadChunkSize))
+}
+
+func validatePath(path string, isDirectory bool) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() != isDirectory {
+		if isDirectory {
+			return fmt.Errorf("%s is not a directory", path)
+		}
+
+		return fmt.Errorf("%s is a directory", path)
+	}
+
+	return nil
+}
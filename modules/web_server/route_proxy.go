@@ -0,0 +1,50 @@
+package web_server
+
+import (
+	"fmt"
+	"kermoo/modules/logger"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"go.uber.org/zap"
+)
+
+func makeReverseProxy(upstream string) (*httputil.ReverseProxy, error) {
+	target, err := url.Parse(upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil, fmt.Errorf("scheme of %s must be either http or https", upstream)
+	}
+
+	if target.Host == "" {
+		return nil, fmt.Errorf("host of %s is missing", upstream)
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		logger.Log.Warn("failed on proxying request", zap.String("upstream", upstream), zap.String("path", r.URL.Path), zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+	}
+
+	return proxy, nil
+}
+
+// serveProxy forwards the request to the upstream.
+func (route *Route) serveProxy(w http.ResponseWriter, r *http.Request) {
+	proxy := route.Content.proxy
+	if proxy == nil {
+		// The route is not validated beforehand
+		var err error
+		if proxy, err = makeReverseProxy(route.Content.Proxy); err != nil {
+			http.Error(w, fmt.Sprintf("unable to proxy: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	proxy.ServeHTTP(w, r)
+}
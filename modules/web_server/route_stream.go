@@ -0,0 +1,256 @@
+package web_server
+
+import (
+	"bytes"
+	"fmt"
+	"kermoo/modules/fluent"
+	"math/rand"
+	"net/http"
+	"text/template"
+	"time"
+)
+
+const (
+	STREAM_FAULT_DROP      = "drop"
+	STREAM_FAULT_SILENCE   = "silence"
+	STREAM_FAULT_MALFORMED = "malformed"
+)
+
+// defaultHeartbeat is the interval of the heartbeats of the streams, if not specified.
+const defaultHeartbeat = 15 * time.Second
+
+// StreamFault defines how the long-lived streams of WebSocket and SSE routes misbehave in
+// the middle of the stream, rather than failing the requests upfront.
+type StreamFault struct {
+	// Types defines how the streams can misbehave when the route is in failing state. The
+	// state is checked on each event, message and heartbeat and one of them is picked
+	// randomly each time:
+	//
+	// - drop: resets the connection in the middle of the stream.
+	// - silence: stops sending events, messages and heartbeats while keeping the connection.
+	// - malformed: sends a malformed event or frame, which the clients should fail on.
+	//
+	// Default is all of them.
+	Types []string `json:"types"`
+}
+
+func (sf *StreamFault) GetTypes() []string {
+	if len(sf.Types) > 0 {
+		return sf.Types
+	}
+
+	return []string{STREAM_FAULT_DROP, STREAM_FAULT_SILENCE, STREAM_FAULT_MALFORMED}
+}
+
+// PickType picks one of the fault types randomly.
+func (sf *StreamFault) PickType() string {
+	types := sf.GetTypes()
+
+	return types[rand.Intn(len(types))]
+}
+
+func (sf *StreamFault) Validate() error {
+	for _, t := range sf.Types {
+		switch t {
+		case STREAM_FAULT_DROP, STREAM_FAULT_SILENCE, STREAM_FAULT_MALFORMED:
+		default:
+			return fmt.Errorf("stream fault type %s is not supported", t)
+		}
+	}
+
+	return nil
+}
+
+// SseContent serves a Server-Sent Events stream which emits an event on each interval.
+type SseContent struct {
+	// Interval decides how often the events are emitted.
+	//
+	// Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Event defines the name of the events.
+	//
+	// Default is no name, which the clients consider as "message".
+	Event string `json:"event"`
+
+	// Data defines the data of the events as a Go text/template which can make use of the
+	// sequence number of the event as .Id and the emission time as .Time.
+	//
+	// Default is the emission time in RFC 3339.
+	Data string `json:"data"`
+
+	// Heartbeat decides how often a comment is sent to keep the stream alive.
+	//
+	// Default is 15 seconds.
+	Heartbeat *fluent.FluentDuration `json:"heartbeat"`
+
+	template *template.Template
+}
+
+// SseEvent is the data which the events are rendered with.
+type SseEvent struct {
+	Id   int64
+	Time time.Time
+}
+
+func (sc *SseContent) GetInterval() time.Duration {
+	if sc.Interval != nil {
+		return sc.Interval.Get()
+	}
+
+	return time.Second
+}
+
+func (sc *SseContent) GetHeartbeat() time.Duration {
+	if sc.Heartbeat != nil {
+		return sc.Heartbeat.Get()
+	}
+
+	return defaultHeartbeat
+}
+
+func (sc *SseContent) Validate() error {
+	if sc.GetInterval() <= 0 {
+		return fmt.Errorf("interval must be greater than zero")
+	}
+
+	if sc.GetHeartbeat() <= 0 {
+		return fmt.Errorf("heartbeat must be greater than zero")
+	}
+
+	if sc.Data != "" {
+		tmpl, err := template.New("sse").Funcs(templateFuncs).Parse(sc.Data)
+		if err != nil {
+			return fmt.Errorf("data is invalid: %v", err)
+		}
+
+		sc.template = tmpl
+	}
+
+	return nil
+}
+
+func (sc *SseContent) render(event SseEvent) ([]byte, error) {
+	if sc.Data == "" {
+		return []byte(event.Time.Format(time.RFC3339)), nil
+	}
+
+	tmpl := sc.template
+	if tmpl == nil {
+		// The content is not validated beforehand
+		var err error
+		if tmpl, err = template.New("sse").Funcs(templateFuncs).Parse(sc.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// pickStreamFault picks a stream fault type when the route is in failing state, or returns
+// an empty string otherwise.
+func (route *Route) pickStreamFault(r *http.Request) string {
+	if route.Fault == nil || route.Fault.Stream == nil {
+		return ""
+	}
+
+	if succeeding, _ := route.getPlanState(); succeeding {
+		return ""
+	}
+
+	t := route.Fault.Stream.PickType()
+
+	markFaulted(r)
+	routeFaultsCounter.Inc(route.webServerName, route.Path, t)
+
+	return t
+}
+
+// serveSse streams the events until the client goes away.
+func (route *Route) serveSse(w http.ResponseWriter, r *http.Request) {
+	sse := route.Content.Sse
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(route.Content.GetStatus())
+	flush(w)
+
+	events := time.NewTicker(sse.GetInterval())
+	defer events.Stop()
+
+	heartbeats := time.NewTicker(sse.GetHeartbeat())
+	defer heartbeats.Stop()
+
+	id := int64(0)
+
+	for {
+		var frame []byte
+		var eventTime *time.Time
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeats.C:
+		case now := <-events.C:
+			eventTime = &now
+		}
+
+		// Faults are applied on the heartbeats too, so that idle streams misbehave as well
+		switch route.pickStreamFault(r) {
+		case STREAM_FAULT_DROP:
+			reset(w)
+			return
+		case STREAM_FAULT_SILENCE:
+			<-r.Context().Done()
+			return
+		case STREAM_FAULT_MALFORMED:
+			// An invalid UTF-8 id along with a truncated data
+			frame = []byte("id: \xff\xfe\ndata: {\"id\":\n\n")
+		default:
+			if eventTime == nil {
+				frame = []byte(": heartbeat\n\n")
+				break
+			}
+
+			id++
+
+			data, err := sse.render(SseEvent{Id: id, Time: *eventTime})
+			if err != nil {
+				data = []byte(fmt.Sprintf("unable to render data: %v", err))
+			}
+
+			frame = makeSseFrame(id, sse.Event, data)
+		}
+
+		if _, err := w.Write(frame); err != nil {
+			// The client is gone
+			return
+		}
+
+		flush(w)
+	}
+}
+
+func makeSseFrame(id int64, event string, data []byte) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "id: %d\n", id)
+
+	if event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event)
+	}
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+
+	buf.WriteString("\n")
+
+	return buf.Bytes()
+}
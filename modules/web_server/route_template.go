@@ -0,0 +1,120 @@
+package web_server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"kermoo/config"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxTemplateBodySize is the maximum number of bytes of the request body which is exposed
+// to the templates.
+const maxTemplateBodySize = 1 << 20
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		content, err := json.Marshal(v)
+		return string(content), err
+	},
+}
+
+// TemplateData is the data which the templates of routes are rendered with.
+type TemplateData struct {
+	Method   string
+	Path     string
+	Params   map[string]string
+	Query    url.Values
+	Headers  http.Header
+	Body     string
+	Env      map[string]string
+	Hostname string
+	Uptime   time.Duration
+	Time     time.Time
+	Cycle    TemplateCycle
+}
+
+// TemplateCycle is the value of the current cycle of the plan assigned to the route.
+type TemplateCycle struct {
+	Percentage float64
+	Size       int64
+	Latency    time.Duration
+	Succeeding bool
+}
+
+func parseRouteTemplate(content string) (*template.Template, error) {
+	return template.New("route").Funcs(templateFuncs).Parse(content)
+}
+
+func (route *Route) renderTemplate(r *http.Request) ([]byte, error) {
+	tmpl := route.Content.template
+	if tmpl == nil {
+		// The route is not validated beforehand
+		var err error
+		if tmpl, err = parseRouteTemplate(route.Content.Template); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, route.makeTemplateData(r)); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (route *Route) makeTemplateData(r *http.Request) TemplateData {
+	now := time.Now()
+	hostname, _ := os.Hostname()
+	body, _ := io.ReadAll(io.LimitReader(r.Body, maxTemplateBodySize))
+
+	env := map[string]string{}
+	for _, variable := range os.Environ() {
+		if name, value, ok := strings.Cut(variable, "="); ok {
+			env[name] = value
+		}
+	}
+
+	return TemplateData{
+		Method:   r.Method,
+		Path:     r.URL.Path,
+		Params:   mux.Vars(r),
+		Query:    r.URL.Query(),
+		Headers:  r.Header,
+		Body:     string(body),
+		Env:      env,
+		Hostname: hostname,
+		Uptime:   now.Sub(config.InitializedAt),
+		Time:     now,
+		Cycle:    route.getTemplateCycle(),
+	}
+}
+
+func (route *Route) getTemplateCycle() TemplateCycle {
+	for _, plan := range route.GetAssignedPlans() {
+		cv := plan.GetCurrentValue()
+
+		// Plan has not started its first cycle yet
+		if cv == nil {
+			continue
+		}
+
+		return TemplateCycle{
+			Percentage: cv.Percentage,
+			Size:       cv.Size,
+			Latency:    cv.Latency,
+			Succeeding: cv.ComputedPercentageChance == nil || *cv.ComputedPercentageChance,
+		}
+	}
+
+	return TemplateCycle{Succeeding: true}
+}
@@ -0,0 +1,323 @@
+package web_server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"kermoo/modules/fluent"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	WEBSOCKET_OPCODE_CONTINUATION = 0x0
+	WEBSOCKET_OPCODE_TEXT         = 0x1
+	WEBSOCKET_OPCODE_BINARY       = 0x2
+	WEBSOCKET_OPCODE_CLOSE        = 0x8
+	WEBSOCKET_OPCODE_PING         = 0x9
+	WEBSOCKET_OPCODE_PONG         = 0xA
+)
+
+// webSocketGuid is the magic string which the accept keys are computed with (RFC 6455).
+const webSocketGuid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxWebSocketPayload is the maximum size of the frames which are accepted from the clients.
+const maxWebSocketPayload = 1 << 20
+
+// webSocketWriteTimeout is how long a frame can take to be written to a client.
+const webSocketWriteTimeout = 10 * time.Second
+
+// WebSocketContent serves a WebSocket endpoint which echoes the messages back.
+type WebSocketContent struct {
+	// Heartbeat decides how often a ping is sent to keep the connection alive.
+	//
+	// Default is 15 seconds.
+	Heartbeat *fluent.FluentDuration `json:"heartbeat"`
+}
+
+func (wc *WebSocketContent) GetHeartbeat() time.Duration {
+	if wc.Heartbeat != nil {
+		return wc.Heartbeat.Get()
+	}
+
+	return defaultHeartbeat
+}
+
+func (wc *WebSocketContent) Validate() error {
+	if wc.GetHeartbeat() <= 0 {
+		return fmt.Errorf("heartbeat must be greater than zero")
+	}
+
+	return nil
+}
+
+// hijackedConnections keeps track of the connections which are taken over from the HTTP
+// server, since the server won't close them on shutdown anymore.
+type hijackedConnections struct {
+	conns map[net.Conn]struct{}
+	mu    sync.Mutex
+}
+
+func (hc *hijackedConnections) add(conn net.Conn) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if hc.conns == nil {
+		hc.conns = map[net.Conn]struct{}{}
+	}
+
+	hc.conns[conn] = struct{}{}
+}
+
+func (hc *hijackedConnections) remove(conn net.Conn) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	delete(hc.conns, conn)
+}
+
+// closeAll closes all of the tracked connections.
+func (hc *hijackedConnections) closeAll() {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	for conn := range hc.conns {
+		conn.Close()
+		delete(hc.conns, conn)
+	}
+}
+
+type webSocketFrame struct {
+	fin     bool
+	opcode  byte
+	payload []byte
+}
+
+// serveWebSocket upgrades the connection and echoes the messages back until the client
+// closes it.
+func (route *Route) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !isWebSocketUpgrade(r) {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "websocket upgrade is expected", http.StatusUpgradeRequired)
+		return
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" || r.Header.Get("Sec-WebSocket-Version") != "13" {
+		http.Error(w, "unsupported websocket handshake", http.StatusBadRequest)
+		return
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to upgrade: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer conn.Close()
+
+	if route.hijacked != nil {
+		route.hijacked.add(conn)
+		defer route.hijacked.remove(conn)
+	}
+
+	write := func(fin bool, opcode byte, payload []byte) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+
+		if err := writeWebSocketFrame(rw.Writer, fin, opcode, payload); err != nil {
+			return err
+		}
+
+		return rw.Writer.Flush()
+	}
+
+	_ = conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+	fmt.Fprintf(rw.Writer, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", makeWebSocketAccept(key))
+
+	if err := rw.Writer.Flush(); err != nil {
+		return
+	}
+
+	frames := make(chan webSocketFrame)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		defer close(frames)
+
+		for {
+			frame, err := readWebSocketFrame(rw.Reader)
+			if err != nil {
+				return
+			}
+
+			select {
+			case frames <- frame:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	heartbeats := time.NewTicker(route.Content.WebSocket.GetHeartbeat())
+	defer heartbeats.Stop()
+
+	silent := false
+
+	for {
+		var frame webSocketFrame
+
+		select {
+		case received, ok := <-frames:
+			if !ok {
+				return
+			}
+
+			switch received.opcode {
+			case WEBSOCKET_OPCODE_CLOSE:
+				_ = write(true, WEBSOCKET_OPCODE_CLOSE, received.payload)
+				return
+			case WEBSOCKET_OPCODE_PING:
+				frame = webSocketFrame{fin: true, opcode: WEBSOCKET_OPCODE_PONG, payload: received.payload}
+			case WEBSOCKET_OPCODE_PONG:
+				continue
+			default:
+				frame = received
+			}
+		case <-heartbeats.C:
+			frame = webSocketFrame{fin: true, opcode: WEBSOCKET_OPCODE_PING}
+		}
+
+		if silent {
+			continue
+		}
+
+		switch route.pickStreamFault(r) {
+		case STREAM_FAULT_DROP:
+			closeWithReset(conn)
+			return
+		case STREAM_FAULT_SILENCE:
+			silent = true
+			continue
+		case STREAM_FAULT_MALFORMED:
+			// All of the reserved bits along with a reserved opcode
+			_ = conn.SetWriteDeadline(time.Now().Add(webSocketWriteTimeout))
+			_, _ = rw.Writer.Write([]byte{0xF3, 0x00})
+			_ = rw.Writer.Flush()
+			continue
+		}
+
+		if err := write(frame.fin, frame.opcode, frame.payload); err != nil {
+			return
+		}
+	}
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	if r.Method != http.MethodGet || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+
+	for _, token := range strings.Split(r.Header.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func makeWebSocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + webSocketGuid))
+
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// readWebSocketFrame reads a frame sent by a client, which is masked by the protocol.
+func readWebSocketFrame(r *bufio.Reader) (webSocketFrame, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return webSocketFrame{}, err
+	}
+
+	frame := webSocketFrame{
+		fin:    header[0]&0x80 != 0,
+		opcode: header[0] & 0x0F,
+	}
+
+	if header[1]&0x80 == 0 {
+		return webSocketFrame{}, fmt.Errorf("frames of the clients must be masked")
+	}
+
+	length := uint64(header[1] & 0x7F)
+
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(r, extended); err != nil {
+			return webSocketFrame{}, err
+		}
+
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(r, extended); err != nil {
+			return webSocketFrame{}, err
+		}
+
+		length = binary.BigEndian.Uint64(extended)
+	}
+
+	if length > maxWebSocketPayload {
+		return webSocketFrame{}, fmt.Errorf("frame of %d bytes is too large", length)
+	}
+
+	mask := make([]byte, 4)
+	if _, err := io.ReadFull(r, mask); err != nil {
+		return webSocketFrame{}, err
+	}
+
+	frame.payload = make([]byte, length)
+	if _, err := io.ReadFull(r, frame.payload); err != nil {
+		return webSocketFrame{}, err
+	}
+
+	for i := range frame.payload {
+		frame.payload[i] ^= mask[i%4]
+	}
+
+	return frame, nil
+}
+
+// writeWebSocketFrame writes an unmasked frame, as the server frames are.
+func writeWebSocketFrame(w io.Writer, fin bool, opcode byte, payload []byte) error {
+	header := []byte{opcode, 0}
+
+	if fin {
+		header[0] |= 0x80
+	}
+
+	switch length := len(payload); {
+	case length < 126:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	_, err := w.Write(payload)
+
+	return err
+}
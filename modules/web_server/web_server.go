@@ -2,12 +2,16 @@ package web_server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"kermoo/config"
 	"kermoo/modules/fluent"
 	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
 	"kermoo/modules/planner"
+	"kermoo/modules/process"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -15,6 +19,8 @@ import (
 	"go.uber.org/zap"
 )
 
+var _ metrics.Collector = &WebServer{}
+
 type WebServerFault struct {
 	// PlanRefs is an optional list of plan names. It can used to avoid redundant
 	// re-declearing of plans in large-scale configurations.
@@ -51,8 +57,8 @@ type WebServer struct {
 	// specifications and response types.
 	//
 	// By default, these routes are defined with no failing conditions: "/", "/livez",
-	// "/readyz", "/healthz". You can define your own routes with your desired failing
-	// conditions.
+	// "/readyz", "/startupz", "/healthz", while "/readyz" and "/startupz" wait for the startup
+	// of the process. You can define your own routes with your desired failing conditions.
 	Routes []*Route `json:"routes"`
 
 	// Interface defines the network interface which the web server should listen
@@ -65,8 +71,25 @@ type WebServer struct {
 	// Fault specifies how the web server should fail. Default is no failure.
 	Fault *WebServerFault `json:"fault"`
 
+	// Limit defines the rate and concurrency limits of the web server, shared by all of
+	// the routes. Default is no limit.
+	Limit *Limit `json:"limit"`
+
+	// Tls enables serving HTTPS, along with HTTP/2, with its own certificate faults.
+	//
+	// Default is plain HTTP.
+	Tls *WebServerTls `json:"tls"`
+
+	// Recorder records the received requests and exposes them as JSON, so that what the
+	// clients actually sent can be inspected.
+	//
+	// Default is no recording.
+	Recorder *RequestRecorder `json:"recorder"`
+
 	server      *http.Server
-	isListening bool
+	isListening atomic.Bool
+	stopped     chan struct{}
+	hijacked    hijackedConnections
 }
 
 func (ws *WebServer) GetName() string {
@@ -113,6 +136,14 @@ func (ws *WebServer) GetRoutes() []*Route {
 			Content: RouteContent{
 				Static: "I'm Ready!",
 			},
+			WaitForStartup: true,
+		},
+		{
+			Path: "/startupz",
+			Content: RouteContent{
+				Static: "I'm Started!",
+			},
+			WaitForStartup: true,
 		},
 		{
 			Path: "/healthz",
@@ -124,6 +155,33 @@ func (ws *WebServer) GetRoutes() []*Route {
 }
 
 func (ws *WebServer) Validate() error {
+	for _, route := range ws.Routes {
+		route.webServerName = ws.GetName()
+		route.hijacked = &ws.hijacked
+	}
+
+	if ws.Limit != nil {
+		ws.Limit.owner = ws.GetName()
+
+		if err := ws.Limit.Validate(); err != nil {
+			return fmt.Errorf("limit is invalid: %v", err)
+		}
+	}
+
+	if ws.Tls != nil {
+		ws.Tls.webServerName = ws.GetName()
+
+		if err := ws.Tls.Validate(); err != nil {
+			return fmt.Errorf("tls is invalid: %v", err)
+		}
+	}
+
+	if ws.Recorder != nil {
+		if err := ws.Recorder.Validate(); err != nil {
+			return fmt.Errorf("recorder is invalid: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -136,22 +194,58 @@ func (ws *WebServer) ListenOnBackground() error {
 
 	r := mux.NewRouter()
 
+	if ws.Recorder != nil {
+		ws.Recorder.registerRoutes(r)
+	}
+
 	for _, route := range ws.GetRoutes() {
 		methods, _ := route.GetMethods()
-		r.HandleFunc(route.Path, route.Handle).Methods(methods...)
+		if route.Content.IsPrefixed() {
+			r.PathPrefix(route.Path).Handler(route.GetHandler()).Methods(methods...)
+		} else {
+			r.Handle(route.Path, route.GetHandler()).Methods(methods...)
+		}
+	}
+
+	var handler http.Handler = r
+	if ws.Limit != nil {
+		handler = ws.Limit.Wrap(r)
 	}
 
+	if ws.Recorder != nil {
+		handler = ws.Recorder.Wrap(handler)
+	}
+
+	handler = holdWhileHung(handler)
+
 	ws.server = &http.Server{
 		Addr:    fmt.Sprintf("%s:%d", ws.GetInterface(), ws.GetPort()),
-		Handler: r,
+		Handler: handler,
+	}
+
+	if ws.Tls != nil {
+		tlsConfig, err := ws.Tls.makeTlsConfig()
+		if err != nil {
+			return err
+		}
+
+		ws.server.TLSConfig = tlsConfig
+		ws.server.TLSNextProto = ws.Tls.makeTlsNextProto()
+
+		// Failed handshakes are expected on faults, so they're not worth more than a warning
+		ws.server.ErrorLog, _ = zap.NewStdLogAt(logger.Log.With(zap.String("webserver", ws.GetName())), zap.WarnLevel)
 	}
 
+	ws.stopped = make(chan struct{})
+
 	go func() {
+		defer close(ws.stopped)
+
 		logger.Log.Info("listening webserver...", zap.String("webserver", ws.GetName()))
 
-		ws.isListening = true
-		if err := ws.server.ListenAndServe(); err != nil {
-			ws.isListening = false
+		ws.isListening.Store(true)
+		if err := ws.listenAndServe(); err != nil {
+			ws.isListening.Store(false)
 
 			if err != http.ErrServerClosed {
 				logger.Log.Fatal(
@@ -168,16 +262,69 @@ func (ws *WebServer) ListenOnBackground() error {
 	return nil
 }
 
+func (ws *WebServer) listenAndServe() error {
+	if ws.Tls != nil {
+		// Certificates are served by the TLS config
+		return ws.server.ListenAndServeTLS("", "")
+	}
+
+	return ws.server.ListenAndServe()
+}
+
+// holdWhileHung makes a handler which holds the requests while the application is hung.
+func holdWhileHung(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		process.WaitWhileHung()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Stop stops the web server right away, without waiting for the in-flight requests.
 func (ws *WebServer) Stop() error {
-	logger.Log.Info("shutting down webserver...", zap.String("webserver", ws.GetName()))
+	return ws.Drain(1 * time.Millisecond)
+}
+
+// Drain stops accepting new connections and waits for the in-flight requests to be served
+// within the given grace period. The remaining connections are closed afterwards.
+func (ws *WebServer) Drain(gracePeriod time.Duration) error {
+	logger.Log.Info("shutting down webserver...", zap.String("webserver", ws.GetName()), zap.Duration("grace_period", gracePeriod))
 	if ws.server == nil {
 		return nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
 	defer cancel()
 
-	return ws.server.Shutdown(ctx)
+	err := ws.server.Shutdown(ctx)
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		logger.Log.Debug("closing remaining connections of webserver...", zap.String("webserver", ws.GetName()))
+		err = ws.server.Close()
+	}
+
+	// Hijacked connections, such as the WebSockets, are not closed by the server itself
+	ws.hijacked.closeAll()
+
+	// Wait for the listener to be released
+	<-ws.stopped
+
+	return err
+}
+
+// IsListening reports whether the web server is up and accepting connections.
+func (ws *WebServer) IsListening() bool {
+	return ws.isListening.Load()
+}
+
+// CollectMetrics exposes the up/down state of the web server.
+func (ws *WebServer) CollectMetrics(w *metrics.Writer) {
+	w.Gauge(
+		"kermoo_webserver_up",
+		"Whether the web server is listening (1) or not (0).",
+		metrics.BoolToFloat(ws.IsListening()),
+		metrics.Label{Name: "webserver", Value: ws.GetName()},
+		metrics.Label{Name: "address", Value: fmt.Sprintf("%s:%d", ws.GetInterface(), ws.GetPort())},
+	)
 }
 
 func (ws *WebServer) HasInlinePlan() bool {
@@ -233,11 +380,11 @@ func (ws *WebServer) GetPlanCycleHooks() planner.CycleHooks {
 	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
 		shouldListen := ws.getPlanPercentageState()
 
-		if shouldListen && !ws.isListening {
+		if shouldListen && !ws.IsListening() {
 			if err := ws.ListenOnBackground(); err != nil {
 				logger.Log.Error("error while listening to webserver", zap.Error(err))
 			}
-		} else if !shouldListen && ws.isListening {
+		} else if !shouldListen && ws.IsListening() {
 			if err := ws.Stop(); err != nil {
 				logger.Log.Error("error while stopping webserver", zap.Error(err))
 			}
@@ -0,0 +1,381 @@
+package web_server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	TLS_FAULT_EXPIRED    = "expired"
+	TLS_FAULT_WRONG_HOST = "wrong-host"
+	TLS_FAULT_UNTRUSTED  = "untrusted"
+	TLS_FAULT_ABORT      = "abort"
+)
+
+// wrongHostname is the only name of the certificate served in the wrong-host fault.
+const wrongHostname = "wrong-host.kermoo.invalid"
+
+var _ planner.Plannable = &WebServerTls{}
+
+var tlsFaultsCounter = metrics.NewCounterVec(
+	"kermoo_webserver_tls_faults_total",
+	"Number of TLS handshakes which are faulted by the web servers.",
+	"webserver", "fault",
+)
+
+var errHandshakeAborted = errors.New("handshake is aborted by the tls fault")
+
+type TlsFault struct {
+	// PlanRefs is an optional list of plan names. It can used to avoid redundant
+	// re-declearing of plans in large-scale configurations.
+	// PlanRefs overrides Percentage, Interval and Duration fields are overrided in favor
+	// of the one defined in the referenced plan.
+	PlanRefs []string `json:"planRefs"`
+
+	// Percentage determines the chance of failing. 0 means no not failure at all and 100
+	// means always failing. By failing, we mean the TLS handshakes will misbehave in one of
+	// the ways defined in Types.
+	//
+	// For specific and ranged declearations, it's going to use that but when an array of
+	// percentages are specified, it'll act like a graph of bars and iterate over them.
+	Percentage fluent.FluentFloat `json:"percentage"`
+
+	// Interval decides how long each desicion to stay failing or serving should last.
+	// A value above one second is recommended but you're free  to use any interval.
+	// Default is one second.
+	Interval *fluent.FluentDuration `json:"interval"`
+
+	// Duration defines the duration of the entire TLS fault. Leave it empty for
+	// life-long running or specify one to end the module completely after that and last decision
+	// will be happening for ever.
+	// In fact, Duration/Interval determines the number of cycle, if defined. Default is empty
+	// for unlimited activity.
+	Duration *fluent.FluentDuration `json:"duration"`
+
+	// Types defines how the TLS handshakes can misbehave when it's in failing state. One of them
+	// is picked randomly for each failing cycle:
+	//
+	// - expired: serves a certificate which is expired.
+	// - wrong-host: serves a certificate which is not valid for the hostnames.
+	// - untrusted: serves a certificate which is signed by an unknown certificate authority.
+	// - abort: aborts the handshakes.
+	//
+	// Default is all of them.
+	Types []string `json:"types"`
+}
+
+func (tf *TlsFault) GetTypes() []string {
+	if len(tf.Types) > 0 {
+		return tf.Types
+	}
+
+	return []string{TLS_FAULT_EXPIRED, TLS_FAULT_WRONG_HOST, TLS_FAULT_UNTRUSTED, TLS_FAULT_ABORT}
+}
+
+// PickType picks one of the fault types randomly.
+func (tf *TlsFault) PickType() string {
+	types := tf.GetTypes()
+
+	return types[rand.Intn(len(types))]
+}
+
+func (tf *TlsFault) Validate() error {
+	for _, t := range tf.Types {
+		switch t {
+		case TLS_FAULT_EXPIRED, TLS_FAULT_WRONG_HOST, TLS_FAULT_UNTRUSTED, TLS_FAULT_ABORT:
+		default:
+			return fmt.Errorf("tls fault type %s is not supported", t)
+		}
+	}
+
+	return nil
+}
+
+type WebServerTls struct {
+	planner.CanAssignPlan
+
+	// CertFile and KeyFile define the PEM encoded certificate and private key to be served
+	// in healthy state.
+	//
+	// Default is a certificate for the Hostnames which is issued by the certificate authority.
+	CertFile *string `json:"certFile"`
+	KeyFile  *string `json:"keyFile"`
+
+	// CaCertFile and CaKeyFile define the PEM encoded certificate authority which issues the
+	// generated certificates, including the expired and wrong-host ones.
+	//
+	// Default is a certificate authority generated on startup.
+	CaCertFile *string `json:"caCertFile"`
+	CaKeyFile  *string `json:"caKeyFile"`
+
+	// ExportCaCert defines a path which the certificate of the certificate authority is
+	// written to, so that the clients can be configured to trust it.
+	//
+	// Default is not exporting.
+	ExportCaCert *string `json:"exportCaCert"`
+
+	// Hostnames defines the DNS names and IP addresses of the generated certificates.
+	//
+	// Default is localhost, 127.0.0.1, ::1 and the hostname of the machine.
+	Hostnames []string `json:"hostnames"`
+
+	// Http2 indicates the web server to negotiate HTTP/2 with the clients.
+	//
+	// Default is true.
+	Http2 *bool `json:"http2"`
+
+	// Fault specifies how the TLS handshakes should fail. Default is no failure.
+	Fault *TlsFault `json:"fault"`
+
+	webServerName string
+	certificates  map[string]*tls.Certificate
+	fault         string
+	mu            sync.Mutex
+}
+
+func (wt *WebServerTls) GetName() string {
+	return fmt.Sprintf("%s-tls", wt.webServerName)
+}
+
+func (wt *WebServerTls) IsHttp2Enabled() bool {
+	return wt.Http2 == nil || *wt.Http2
+}
+
+func (wt *WebServerTls) GetHostnames() []string {
+	if len(wt.Hostnames) > 0 {
+		return wt.Hostnames
+	}
+
+	hostnames := []string{"localhost", "127.0.0.1", "::1"}
+
+	if hostname, err := os.Hostname(); err == nil && hostname != "localhost" {
+		hostnames = append(hostnames, hostname)
+	}
+
+	return hostnames
+}
+
+func (wt *WebServerTls) Validate() error {
+	if (wt.CertFile == nil) != (wt.KeyFile == nil) {
+		return fmt.Errorf("cert file and key file must be defined together")
+	}
+
+	if (wt.CaCertFile == nil) != (wt.CaKeyFile == nil) {
+		return fmt.Errorf("ca cert file and ca key file must be defined together")
+	}
+
+	if wt.CertFile != nil {
+		if _, err := tls.LoadX509KeyPair(*wt.CertFile, *wt.KeyFile); err != nil {
+			return fmt.Errorf("unable to load certificate: %v", err)
+		}
+	}
+
+	if wt.CaCertFile != nil {
+		if _, err := loadCertificateAuthority(*wt.CaCertFile, *wt.CaKeyFile); err != nil {
+			return fmt.Errorf("unable to load certificate authority: %v", err)
+		}
+	}
+
+	if wt.Fault != nil {
+		return wt.Fault.Validate()
+	}
+
+	return nil
+}
+
+// GetFault returns the fault which is applied on the upcoming handshakes, or an empty
+// string when the handshakes are healthy.
+func (wt *WebServerTls) GetFault() string {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	return wt.fault
+}
+
+// SetFault sets the fault to be applied on the upcoming handshakes.
+func (wt *WebServerTls) SetFault(fault string) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	wt.fault = fault
+}
+
+// makeTlsConfig prepares the certificates, once, and makes a TLS config which serves them.
+func (wt *WebServerTls) makeTlsConfig() (*tls.Config, error) {
+	if err := wt.prepareCertificates(); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: wt.getCertificate,
+	}, nil
+}
+
+// makeTlsNextProto returns a non-nil map to disable HTTP/2 when it's not enabled.
+func (wt *WebServerTls) makeTlsNextProto() map[string]func(*http.Server, *tls.Conn, http.Handler) {
+	if wt.IsHttp2Enabled() {
+		return nil
+	}
+
+	return map[string]func(*http.Server, *tls.Conn, http.Handler){}
+}
+
+func (wt *WebServerTls) prepareCertificates() error {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	if wt.certificates != nil {
+		return nil
+	}
+
+	var ca *certificateAuthority
+	var err error
+
+	if wt.CaCertFile != nil {
+		ca, err = loadCertificateAuthority(*wt.CaCertFile, *wt.CaKeyFile)
+	} else {
+		ca, err = newCertificateAuthority("Kermoo CA")
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to prepare certificate authority: %v", err)
+	}
+
+	if wt.ExportCaCert != nil {
+		if err := os.WriteFile(*wt.ExportCaCert, ca.pem, 0644); err != nil {
+			return fmt.Errorf("unable to export certificate authority: %v", err)
+		}
+	}
+
+	hostnames := wt.GetHostnames()
+	now := time.Now()
+	certificates := map[string]*tls.Certificate{}
+
+	if wt.CertFile != nil {
+		pair, err := tls.LoadX509KeyPair(*wt.CertFile, *wt.KeyFile)
+		if err != nil {
+			return fmt.Errorf("unable to load certificate: %v", err)
+		}
+
+		certificates[""] = &pair
+	} else if certificates[""], err = ca.issue(hostnames, now.Add(-time.Hour), now.AddDate(1, 0, 0)); err != nil {
+		return err
+	}
+
+	if certificates[TLS_FAULT_EXPIRED], err = ca.issue(hostnames, now.AddDate(0, 0, -2), now.AddDate(0, 0, -1)); err != nil {
+		return err
+	}
+
+	if certificates[TLS_FAULT_WRONG_HOST], err = ca.issue([]string{wrongHostname}, now.Add(-time.Hour), now.AddDate(1, 0, 0)); err != nil {
+		return err
+	}
+
+	untrustedCa, err := newCertificateAuthority("Kermoo Untrusted CA")
+	if err != nil {
+		return err
+	}
+
+	if certificates[TLS_FAULT_UNTRUSTED], err = untrustedCa.issue(hostnames, now.Add(-time.Hour), now.AddDate(1, 0, 0)); err != nil {
+		return err
+	}
+
+	wt.certificates = certificates
+
+	return nil
+}
+
+func (wt *WebServerTls) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	wt.mu.Lock()
+	defer wt.mu.Unlock()
+
+	if wt.fault != "" {
+		tlsFaultsCounter.Inc(wt.webServerName, wt.fault)
+	}
+
+	if wt.fault == TLS_FAULT_ABORT {
+		return nil, errHandshakeAborted
+	}
+
+	return wt.certificates[wt.fault], nil
+}
+
+func (wt *WebServerTls) HasInlinePlan() bool {
+	return wt.MakeInlinePlan() != nil
+}
+
+func (wt *WebServerTls) MakeInlinePlan() *planner.Plan {
+	if wt.Fault == nil {
+		return nil
+	}
+
+	plan := planner.NewPlan(planner.Plan{
+		Percentage: &wt.Fault.Percentage,
+		Interval:   wt.Fault.Interval,
+		Duration:   wt.Fault.Duration,
+	})
+
+	return &plan
+}
+
+// Create a lifetime-long plan to serve healthy handshakes
+func (wt *WebServerTls) MakeDefaultPlan() *planner.Plan {
+	plan := planner.NewPlan(planner.Plan{})
+
+	// Value of 0.0 indicates that the handshakes will never fail.
+	plan.Percentage = fluent.NewMustFluentFloat("0.0")
+
+	return &plan
+}
+
+func (wt *WebServerTls) GetDesiredPlanNames() []string {
+	if wt.Fault == nil {
+		return nil
+	}
+
+	return wt.Fault.PlanRefs
+}
+
+func (wt *WebServerTls) getPlanPercentageState() bool {
+	for _, plan := range wt.GetAssignedPlans() {
+		if !*plan.GetCurrentValue().ComputedPercentageChance {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (wt *WebServerTls) GetPlanCycleHooks() planner.CycleHooks {
+	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		fault := ""
+
+		if !wt.getPlanPercentageState() && wt.Fault != nil {
+			fault = wt.Fault.PickType()
+		}
+
+		if fault != wt.GetFault() {
+			logger.Log.Info("changing tls fault", zap.String("webserver", wt.webServerName), zap.String("fault", fault))
+		}
+
+		wt.SetFault(fault)
+
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	return planner.CycleHooks{
+		PreSleep: &preSleep,
+	}
+}
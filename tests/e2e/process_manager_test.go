@@ -3,6 +3,8 @@ package e2e_test
 import (
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestProcessManagerEndToEnd(t *testing.T) {
@@ -75,4 +77,57 @@ func TestProcessManagerEndToEnd(t *testing.T) {
 		e2e.AssertExitCode(20)
 		e2e.AssertExecutaionDuration(2*time.Second, 5*time.Second)
 	})
+
+	t.Run("crash with one of exit codes", func(t *testing.T) {
+		e2e := NewE2E(t)
+
+		e2e.Start(`
+            process:
+              crash:
+                percentage: 100
+                interval: 100ms
+                types: [exit]
+                exitCodes: [42]
+		`, 10*time.Second)
+
+		e2e.Wait()
+
+		e2e.RequireNotTimedOut()
+		e2e.AssertExitCode(42)
+	})
+
+	t.Run("crash with panic", func(t *testing.T) {
+		e2e := NewE2E(t)
+
+		e2e.Start(`
+            process:
+              crash:
+                percentage: 100
+                interval: 100ms
+                types: [panic]
+		`, 10*time.Second)
+
+		e2e.Wait()
+
+		e2e.RequireNotTimedOut()
+		e2e.AssertExitCode(2)
+		assert.Contains(t, e2e.GetOutput(), "panic: crashing the process on purpose")
+	})
+
+	t.Run("failing initialization", func(t *testing.T) {
+		e2e := NewE2E(t)
+
+		e2e.Start(`
+            process:
+              startup:
+                duration: 500ms
+                failurePercentage: 100
+                exitCode: 3
+		`, 10*time.Second)
+
+		e2e.Wait()
+
+		e2e.RequireNotTimedOut()
+		e2e.AssertExitCode(3)
+	})
 }
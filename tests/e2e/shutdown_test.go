@@ -0,0 +1,66 @@
+package e2e_test
+
+import (
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownEndToEnd(t *testing.T) {
+	t.Run("drains webservers and exits on interrupt", func(t *testing.T) {
+		e2e := NewE2E(t)
+
+		e2e.Start(`
+            process:
+              shutdown:
+                exitCode: 7
+                exitDelay: 1s
+            webServers:
+            - port: 8080
+              routes:
+              - path: /slow
+                fault:
+                  responseDelay: 1s
+		`, 20*time.Second)
+
+		require.Eventually(t, func() bool {
+			resp, err := http.Get("http://0.0.0.0:8080/")
+			if err != nil {
+				return false
+			}
+			resp.Body.Close()
+
+			return true
+		}, 15*time.Second, 200*time.Millisecond, "webserver should be up")
+
+		status := make(chan int, 1)
+
+		go func() {
+			resp, err := http.Get("http://0.0.0.0:8080/slow")
+			if err != nil {
+				status <- 0
+				return
+			}
+			resp.Body.Close()
+
+			status <- resp.StatusCode
+		}()
+
+		// Let the request get in
+		time.Sleep(200 * time.Millisecond)
+
+		signaledAt := time.Now()
+		e2e.Signal(syscall.SIGINT)
+
+		e2e.Wait()
+
+		e2e.RequireNotTimedOut()
+		e2e.AssertExitCode(7)
+		assert.Equal(t, http.StatusOK, <-status, "in-flight request should be served")
+		assert.GreaterOrEqual(t, time.Since(signaledAt), 1*time.Second)
+	})
+}
@@ -184,6 +184,17 @@ func (e *E2E) Wait() {
 	e.endedAt = time.Now()
 }
 
+// Signal sends the signal to the process. When it's started by `go run`, the signal is sent
+// to the whole process group, which `go run` relays the interrupts to.
+func (e *E2E) Signal(sig syscall.Signal) {
+	if e.GetKermooBinaryPath() != "" {
+		require.NoError(e.t, e.cmd.Process.Signal(sig))
+		return
+	}
+
+	require.NoError(e.t, syscall.Kill(-e.cmd.Process.Pid, sig))
+}
+
 func (e *E2E) AssertExecutaionDuration(min time.Duration, max time.Duration) {
 	assert.GreaterOrEqual(e.t, e.endedAt.Sub(e.startedAt), min)
 	assert.LessOrEqual(e.t, e.endedAt.Sub(e.startedAt), max)
@@ -0,0 +1,155 @@
+package admin_test
+
+import (
+	"encoding/json"
+	"kermoo/modules/admin"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeAdmin(t *testing.T) (*admin.Admin, *planner.Plan) {
+	name := "disaster"
+
+	plan := planner.NewPlan(planner.Plan{
+		Name: &name,
+		SubPlans: []planner.SubPlan{
+			{
+				Percentage: fluent.NewMustFluentFloat("0"),
+				Interval:   fluent.NewMustFluentDuration("10ms"),
+			},
+			{
+				Percentage: fluent.NewMustFluentFloat("100"),
+				Interval:   fluent.NewMustFluentDuration("10ms"),
+			},
+		},
+	})
+
+	require.NoError(t, plan.Validate())
+	t.Cleanup(plan.Stop)
+
+	a := &admin.Admin{}
+	a.SetPlans([]*planner.Plan{&plan})
+
+	return a, &plan
+}
+
+func sendRequest(a *admin.Admin, method string, path string, body string) (*httptest.ResponseRecorder, admin.PlanStatus) {
+	w := httptest.NewRecorder()
+	a.GetRouter().ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+
+	status := admin.PlanStatus{}
+	_ = json.Unmarshal(w.Body.Bytes(), &status)
+
+	return w, status
+}
+
+func TestAdmin(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	t.Run("lists plans", func(t *testing.T) {
+		a, _ := makeAdmin(t)
+
+		w := httptest.NewRecorder()
+		a.GetRouter().ServeHTTP(w, httptest.NewRequest("GET", "/plans", nil))
+
+		statuses := []admin.PlanStatus{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &statuses))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, statuses, 1)
+		assert.Equal(t, "disaster", statuses[0].Name)
+		assert.Equal(t, planner.PLAN_STATE_PENDING, statuses[0].State)
+	})
+
+	t.Run("responds not found for unknown plans", func(t *testing.T) {
+		a, _ := makeAdmin(t)
+
+		w, _ := sendRequest(a, "GET", "/plans/unknown", "")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("pauses, resumes and skips a plan", func(t *testing.T) {
+		a, plan := makeAdmin(t)
+
+		go plan.Start()
+		time.Sleep(30 * time.Millisecond)
+
+		w, status := sendRequest(a, "POST", "/plans/disaster/pause", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, planner.PLAN_STATE_PAUSED, status.State)
+
+		time.Sleep(30 * time.Millisecond)
+		cycle := plan.GetCycleIndex()
+		time.Sleep(30 * time.Millisecond)
+		assert.Equal(t, cycle, plan.GetCycleIndex(), "paused plan should not proceed")
+
+		w, status = sendRequest(a, "POST", "/plans/disaster/resume", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, planner.PLAN_STATE_RUNNING, status.State)
+
+		w, _ = sendRequest(a, "POST", "/plans/disaster/skip", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		time.Sleep(30 * time.Millisecond)
+
+		_, status = sendRequest(a, "GET", "/plans/disaster", "")
+		assert.Equal(t, 1, status.SubPlan)
+		assert.Equal(t, float64(100), status.Value.Percentage)
+
+		w, _ = sendRequest(a, "POST", "/plans/disaster/skip", "")
+		assert.Equal(t, http.StatusConflict, w.Code, "last sub-plan can not be skipped")
+	})
+
+	t.Run("overrides a plan temporarily", func(t *testing.T) {
+		a, plan := makeAdmin(t)
+
+		go plan.Start()
+		time.Sleep(30 * time.Millisecond)
+
+		w, status := sendRequest(a, "POST", "/plans/disaster/override", `{"percentage": 100, "size": "10Mi", "duration": "50ms"}`)
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.NotNil(t, status.Override)
+		assert.Equal(t, float64(100), status.Value.Percentage)
+		assert.Equal(t, int64(10*1024*1024), status.Value.Size)
+
+		time.Sleep(80 * time.Millisecond)
+
+		_, status = sendRequest(a, "GET", "/plans/disaster", "")
+		assert.Nil(t, status.Override)
+		assert.Equal(t, float64(0), status.Value.Percentage)
+	})
+
+	t.Run("fails to override without duration", func(t *testing.T) {
+		a, _ := makeAdmin(t)
+
+		w, _ := sendRequest(a, "POST", "/plans/disaster/override", `{"percentage": 100}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	})
+
+	t.Run("exposes metrics of the collectors", func(t *testing.T) {
+		a, plan := makeAdmin(t)
+		a.SetCollectors([]metrics.Collector{plan})
+
+		go plan.Start()
+		time.Sleep(30 * time.Millisecond)
+
+		w := httptest.NewRecorder()
+		a.GetRouter().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/plain")
+		assert.Contains(t, w.Body.String(), "# TYPE kermoo_plan_percentage gauge")
+		assert.Contains(t, w.Body.String(), `kermoo_plan_running{plan="disaster"} 1`)
+	})
+}
@@ -0,0 +1,184 @@
+package disk_test
+
+import (
+	"kermoo/modules/disk"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/metrics"
+	"kermoo/modules/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getFileSize(t *testing.T, path string) int64 {
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	return info.Size()
+}
+
+func TestDiskFillValidate(t *testing.T) {
+	t.Run("should return error when no plan or plan refs is set", func(t *testing.T) {
+		err := (&disk.DiskFill{}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "plan refs")
+	})
+
+	t.Run("should return error when directory is a file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(file, []byte("x"), 0644))
+
+		err := (&disk.DiskFill{Size: fluent.NewMustFluentSize("1Ki"), Directory: &file}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not a directory")
+	})
+
+	t.Run("should accept a missing directory", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "missing")
+		assert.NoError(t, (&disk.DiskFill{Size: fluent.NewMustFluentSize("1Ki"), Directory: &dir}).Validate())
+	})
+}
+
+func TestDiskFill(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	dir := filepath.Join(t.TempDir(), "fill")
+	df := &disk.DiskFill{Directory: &dir}
+
+	require.NoError(t, df.Fill(3*1024*1024+100))
+	assert.Equal(t, int64(3*1024*1024+100), getFileSize(t, df.GetFilePath()))
+	assert.Equal(t, int64(3*1024*1024+100), df.GetFilledSize())
+
+	require.NoError(t, df.Fill(1024))
+	assert.Equal(t, int64(1024), getFileSize(t, df.GetFilePath()))
+	assert.Equal(t, int64(1024), df.GetFilledSize())
+
+	df.Stop()
+	assert.NoFileExists(t, df.GetFilePath())
+	assert.Equal(t, int64(0), df.GetFilledSize())
+}
+
+func TestDiskFillPlan(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	dir := t.TempDir()
+	df := &disk.DiskFill{
+		Directory: &dir,
+		Size:      fluent.NewMustFluentSize("1Ki, 2Ki"),
+		Interval:  fluent.NewMustFluentDuration("50ms"),
+		Duration:  fluent.NewMustFluentDuration("200ms"),
+	}
+	require.NoError(t, df.Validate())
+
+	plan := df.MakeInlinePlan()
+	plan.Name = utils.NewP[string]("disk-fill")
+	plan.Assign(df)
+
+	t.Cleanup(plan.Stop)
+
+	go plan.Start()
+
+	assert.Eventually(t, func() bool {
+		return df.GetFilledSize() == 1024
+	}, 40*time.Millisecond, time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return df.GetFilledSize() == 2048
+	}, 100*time.Millisecond, time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(df.GetFilePath())
+		return os.IsNotExist(err)
+	}, 500*time.Millisecond, 5*time.Millisecond, "fill file should be removed when the plan ends")
+}
+
+func TestDiskIOValidate(t *testing.T) {
+	throughput := fluent.NewMustFluentSize("1Mi")
+
+	t.Run("should return error when no plan or plan refs is set", func(t *testing.T) {
+		err := (&disk.DiskIO{}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "plan refs")
+	})
+
+	t.Run("should return error on unknown mode", func(t *testing.T) {
+		err := (&disk.DiskIO{Throughput: throughput, Mode: utils.NewP[string]("append")}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "mode")
+	})
+
+	t.Run("should return error when block size and iops are both set", func(t *testing.T) {
+		err := (&disk.DiskIO{Throughput: throughput, BlockSize: fluent.NewMustFluentSize("4Ki"), Iops: utils.NewP[int64](10)}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "can not be used together")
+	})
+
+	t.Run("should return error on non-positive iops", func(t *testing.T) {
+		err := (&disk.DiskIO{Throughput: throughput, Iops: utils.NewP[int64](0)}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "iops")
+	})
+
+	t.Run("should return error on too large block size", func(t *testing.T) {
+		err := (&disk.DiskIO{Throughput: throughput, BlockSize: fluent.NewMustFluentSize("1Gi")}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "block size")
+	})
+
+	t.Run("should accept a valid config", func(t *testing.T) {
+		assert.NoError(t, (&disk.DiskIO{Throughput: throughput, Mode: utils.NewP[string]("readwrite"), Iops: utils.NewP[int64](100)}).Validate())
+	})
+}
+
+func TestDiskIOBlockSize(t *testing.T) {
+	assert.Equal(t, int64(4*1024), (&disk.DiskIO{}).GetBlockSize(1024*1024))
+	assert.Equal(t, int64(8*1024), (&disk.DiskIO{BlockSize: fluent.NewMustFluentSize("8Ki")}).GetBlockSize(1024*1024))
+	assert.Equal(t, int64(10*1024), (&disk.DiskIO{Iops: utils.NewP[int64](100)}).GetBlockSize(1000*1024))
+	assert.Equal(t, int64(64*1024*1024), (&disk.DiskIO{Iops: utils.NewP[int64](1)}).GetBlockSize(1024*1024*1024))
+}
+
+func TestDiskIO(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	t.Run("writes with the given throughput and fsyncs", func(t *testing.T) {
+		dir := t.TempDir()
+		dio := &disk.DiskIO{Directory: &dir, Fsync: utils.NewP[bool](true)}
+
+		require.NoError(t, dio.Start(1024*1024))
+		time.Sleep(550 * time.Millisecond)
+		dio.Stop()
+
+		sb := getMetrics(dio)
+		assert.Contains(t, sb, "kermoo_disk_io_target_bytes_per_second 0")
+		assert.Regexp(t, `kermoo_disk_io_written_bytes_total (5|6)\d{5}\n`, sb, "around half a megabyte should be written")
+		assert.Contains(t, sb, "kermoo_disk_io_read_bytes_total 0")
+		assert.NotContains(t, sb, "kermoo_disk_io_fsyncs_total 0")
+		assert.NoFileExists(t, dio.GetFilePath())
+	})
+
+	t.Run("reads in read mode", func(t *testing.T) {
+		dir := t.TempDir()
+		dio := &disk.DiskIO{Directory: &dir, Mode: utils.NewP[string]("read")}
+
+		require.NoError(t, dio.Start(1024*1024))
+		time.Sleep(250 * time.Millisecond)
+		dio.Stop()
+
+		sb := getMetrics(dio)
+		assert.Contains(t, sb, "kermoo_disk_io_written_bytes_total 0")
+		assert.NotContains(t, sb, "kermoo_disk_io_read_bytes_total 0")
+	})
+}
+
+func getMetrics(dio *disk.DiskIO) string {
+	sb := strings.Builder{}
+	_, _ = metrics.Gather([]metrics.Collector{dio}).WriteTo(&sb)
+
+	return sb.String()
+}
@@ -0,0 +1,134 @@
+package fd_test
+
+import (
+	"kermoo/modules/fd"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/utils"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func countOpenDescriptors(t *testing.T) int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("open file descriptors can not be counted on this platform")
+	}
+
+	return len(entries)
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("should return error when no plan or plan refs is set", func(t *testing.T) {
+		err := (&fd.FdLeak{}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "plan refs")
+	})
+
+	t.Run("should return error on unknown kind", func(t *testing.T) {
+		err := (&fd.FdLeak{Count: fluent.NewMustFluentSize("10"), Kind: utils.NewP[string]("inotify")}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "kind")
+	})
+
+	t.Run("should accept a valid config", func(t *testing.T) {
+		assert.NoError(t, (&fd.FdLeak{Count: fluent.NewMustFluentSize("10 to 100"), Kind: utils.NewP[string]("sockets")}).Validate())
+	})
+}
+
+func TestMakeInlinePlan(t *testing.T) {
+	count := fluent.NewMustFluentSize("100, 200")
+	leak := fd.FdLeak{Count: count}
+
+	assert.Equal(t, count, leak.MakeInlinePlan().Size)
+	assert.Nil(t, (&fd.FdLeak{}).MakeInlinePlan())
+	assert.Nil(t, leak.MakeDefaultPlan())
+}
+
+func TestStartAndStopLeaking(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	for _, kind := range []string{fd.FD_KIND_FILES, fd.FD_KIND_PIPES, fd.FD_KIND_SOCKETS} {
+		kind := kind
+
+		t.Run(kind, func(t *testing.T) {
+			leak := &fd.FdLeak{Kind: &kind}
+			defer leak.Stop()
+
+			// Warm up the lazily opened resources such as the local listener
+			leak.StartLeaking(2)
+			leak.StopLeaking()
+
+			before := countOpenDescriptors(t)
+
+			leak.StartLeaking(50)
+			assert.Equal(t, int64(50), leak.GetLeakedCount())
+			assert.Equal(t, before+50, countOpenDescriptors(t))
+
+			leak.StopLeaking()
+			assert.Equal(t, int64(0), leak.GetLeakedCount())
+			assert.Equal(t, before, countOpenDescriptors(t))
+		})
+	}
+}
+
+func TestLeakingUpToTheLimit(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	open := countOpenDescriptors(t)
+
+	original := syscall.Rlimit{}
+	require.NoError(t, syscall.Getrlimit(syscall.RLIMIT_NOFILE, &original))
+
+	limited := original
+	limited.Cur = uint64(open + 20)
+	require.NoError(t, syscall.Setrlimit(syscall.RLIMIT_NOFILE, &limited))
+	defer func() {
+		require.NoError(t, syscall.Setrlimit(syscall.RLIMIT_NOFILE, &original))
+	}()
+
+	leak := &fd.FdLeak{}
+	leak.StartLeaking(100)
+	defer leak.StopLeaking()
+
+	assert.Less(t, leak.GetLeakedCount(), int64(100), "leak should stop on reaching the limit")
+	assert.Greater(t, leak.GetLeakedCount(), int64(0))
+}
+
+func TestLeakingSocketsUpToTheLimit(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	goroutines := runtime.NumGoroutine()
+	open := countOpenDescriptors(t)
+
+	original := syscall.Rlimit{}
+	require.NoError(t, syscall.Getrlimit(syscall.RLIMIT_NOFILE, &original))
+
+	limited := original
+	limited.Cur = uint64(open + 21)
+	require.NoError(t, syscall.Setrlimit(syscall.RLIMIT_NOFILE, &limited))
+	defer func() {
+		require.NoError(t, syscall.Setrlimit(syscall.RLIMIT_NOFILE, &original))
+	}()
+
+	kind := fd.FD_KIND_SOCKETS
+	leak := &fd.FdLeak{Kind: &kind}
+
+	// Failing accepts should neither block the leak nor the next ones
+	for i := 0; i < 3; i++ {
+		leak.StartLeaking(100)
+		assert.Less(t, leak.GetLeakedCount(), int64(100), "leak should stop on reaching the limit")
+		leak.StopLeaking()
+	}
+
+	leak.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines, "no goroutine should be left behind")
+}
@@ -0,0 +1,104 @@
+package goroutine_test
+
+import (
+	"kermoo/modules/fluent"
+	"kermoo/modules/goroutine"
+	"kermoo/modules/logger"
+	"kermoo/modules/utils"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("should return error when no plan or plan refs is set", func(t *testing.T) {
+		err := (&goroutine.GoroutineLeak{}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "plan refs")
+	})
+
+	t.Run("should return error on non-positive limit", func(t *testing.T) {
+		err := (&goroutine.GoroutineLeak{Rate: fluent.NewMustFluentSize("10"), Limit: utils.NewP[int64](0)}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "limit")
+	})
+
+	t.Run("should return error on thread limit close to the one of go runtime", func(t *testing.T) {
+		leak := &goroutine.GoroutineLeak{Rate: fluent.NewMustFluentSize("10"), LockOSThread: utils.NewP[bool](true), Limit: utils.NewP[int64](10000)}
+		require.Error(t, leak.Validate())
+
+		leak.LockOSThread = utils.NewP[bool](false)
+		require.NoError(t, leak.Validate())
+	})
+}
+
+func TestGetLimit(t *testing.T) {
+	assert.Equal(t, int64(0), (&goroutine.GoroutineLeak{}).GetLimit())
+	assert.Equal(t, int64(5000), (&goroutine.GoroutineLeak{LockOSThread: utils.NewP[bool](true)}).GetLimit())
+	assert.Equal(t, int64(10), (&goroutine.GoroutineLeak{LockOSThread: utils.NewP[bool](true), Limit: utils.NewP[int64](10)}).GetLimit())
+}
+
+func TestLeak(t *testing.T) {
+	t.Run("leaks goroutines up to the limit", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+		leak := &goroutine.GoroutineLeak{Limit: utils.NewP[int64](150)}
+
+		leak.Leak(100)
+		assert.Equal(t, int64(100), leak.GetLeakedCount())
+		assert.Equal(t, int64(100), goroutine.GetLeakedGoroutines())
+		assert.GreaterOrEqual(t, runtime.NumGoroutine(), before+100)
+
+		leak.Leak(100)
+		assert.Equal(t, int64(150), leak.GetLeakedCount())
+
+		leak.Stop()
+		assert.Equal(t, int64(0), leak.GetLeakedCount())
+		assert.Equal(t, int64(0), goroutine.GetLeakedGoroutines())
+		assert.Less(t, runtime.NumGoroutine(), before+50, "released goroutines should exit")
+	})
+
+	t.Run("locks os threads", func(t *testing.T) {
+		leak := &goroutine.GoroutineLeak{LockOSThread: utils.NewP[bool](true)}
+
+		leak.Leak(20)
+		assert.Equal(t, int64(20), goroutine.GetLockedThreads())
+
+		// Idle threads might be taken by the locked goroutines, so the thread count is at
+		// least the number of locked goroutines plus the one running this test.
+		assert.Eventually(t, func() bool {
+			return utils.GetThreadCount() > 20
+		}, time.Second, 10*time.Millisecond)
+
+		leak.Stop()
+		assert.Equal(t, int64(0), goroutine.GetLockedThreads())
+	})
+}
+
+func TestLeakPlan(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	leak := &goroutine.GoroutineLeak{
+		Rate:     fluent.NewMustFluentSize("10"),
+		Interval: fluent.NewMustFluentDuration("20ms"),
+		Duration: fluent.NewMustFluentDuration("100ms"),
+	}
+	require.NoError(t, leak.Validate())
+
+	plan := leak.MakeInlinePlan()
+	plan.Name = utils.NewP[string]("goroutine-leak")
+	plan.Assign(leak)
+	t.Cleanup(plan.Stop)
+
+	go plan.Start()
+
+	assert.Eventually(t, func() bool {
+		return leak.GetLeakedCount() >= 30
+	}, 200*time.Millisecond, time.Millisecond, "goroutines should pile up on each cycle")
+
+	assert.Eventually(t, func() bool {
+		return leak.GetLeakedCount() == 0
+	}, 500*time.Millisecond, 5*time.Millisecond, "goroutines should be released when the plan ends")
+}
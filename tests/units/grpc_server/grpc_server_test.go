@@ -0,0 +1,155 @@
+package grpc_server_test
+
+import (
+	"context"
+	"kermoo/modules/grpc_server"
+	"kermoo/modules/logger"
+	"kermoo/modules/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func startServer(t *testing.T, gs *grpc_server.GrpcServer, fault string) *grpc.ClientConn {
+	require.NoError(t, gs.SetFault(fault))
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.Dial(gs.GetAddress(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func check(conn *grpc.ClientConn, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, err
+	}
+
+	return resp.Status, nil
+}
+
+func echo(conn *grpc.ClientConn, message string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	out := &wrapperspb.StringValue{}
+	if err := conn.Invoke(ctx, "/kermoo.Echo/Echo", wrapperspb.String(message), out); err != nil {
+		return "", err
+	}
+
+	return out.Value, nil
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("requires port", func(t *testing.T) {
+		err := (&grpc_server.GrpcServer{}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "port")
+	})
+
+	t.Run("rejects unknown fault type", func(t *testing.T) {
+		err := (&grpc_server.GrpcServer{
+			Port:  utils.NewP[int32](8601),
+			Fault: &grpc_server.GrpcServerFault{Types: []string{"explode"}},
+		}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "explode")
+	})
+}
+
+func TestServing(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	gs := &grpc_server.GrpcServer{
+		Interface: utils.NewP("127.0.0.1"),
+		Port:      utils.NewP[int32](8602),
+	}
+	conn := startServer(t, gs, "")
+
+	t.Run("reports serving health", func(t *testing.T) {
+		for _, service := range []string{"", grpc_server.ECHO_SERVICE} {
+			servingStatus, err := check(conn, service)
+			require.NoError(t, err)
+			assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus)
+		}
+	})
+
+	t.Run("echoes messages back", func(t *testing.T) {
+		message, err := echo(conn, "hello")
+		require.NoError(t, err)
+		assert.Equal(t, "hello", message)
+	})
+
+	t.Run("stops listening", func(t *testing.T) {
+		assert.True(t, gs.IsListening())
+		gs.Stop()
+		assert.False(t, gs.IsListening())
+
+		_, err := check(conn, "")
+		assert.Error(t, err)
+	})
+}
+
+func TestFaults(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	gs := &grpc_server.GrpcServer{
+		Interface: utils.NewP("127.0.0.1"),
+		Port:      utils.NewP[int32](8603),
+	}
+	conn := startServer(t, gs, "")
+
+	tt := []struct {
+		fault string
+		code  codes.Code
+	}{
+		{fault: grpc_server.GRPC_FAULT_UNAVAILABLE, code: codes.Unavailable},
+		{fault: grpc_server.GRPC_FAULT_RESOURCE_EXHAUSTED, code: codes.ResourceExhausted},
+		{fault: grpc_server.GRPC_FAULT_DEADLINE_EXCEEDED, code: codes.DeadlineExceeded},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.fault, func(t *testing.T) {
+			require.NoError(t, gs.SetFault(tc.fault))
+
+			_, err := echo(conn, "hello")
+			assert.Equal(t, tc.code, status.Code(err))
+
+			_, err = check(conn, "")
+			assert.Equal(t, tc.code, status.Code(err))
+		})
+	}
+
+	t.Run(grpc_server.GRPC_FAULT_NOT_SERVING, func(t *testing.T) {
+		require.NoError(t, gs.SetFault(grpc_server.GRPC_FAULT_NOT_SERVING))
+
+		servingStatus, err := check(conn, "")
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus)
+
+		message, err := echo(conn, "hello")
+		require.NoError(t, err)
+		assert.Equal(t, "hello", message)
+	})
+
+	t.Run("recovers", func(t *testing.T) {
+		require.NoError(t, gs.SetFault(""))
+
+		servingStatus, err := check(conn, "")
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus)
+	})
+}
@@ -0,0 +1,156 @@
+package metrics_test
+
+import (
+	"fmt"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/memory"
+	"kermoo/modules/metrics"
+	"kermoo/modules/planner"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gather(collectors ...metrics.Collector) string {
+	sb := strings.Builder{}
+	_, _ = metrics.Gather(collectors).WriteTo(&sb)
+
+	return sb.String()
+}
+
+func TestWriter(t *testing.T) {
+	t.Run("writes samples grouped by their family", func(t *testing.T) {
+		w := metrics.NewWriter()
+		w.Gauge("kermoo_test_gauge", "A test gauge.", 1.5, metrics.Label{Name: "name", Value: "a"})
+		w.Counter("kermoo_test_counter", "A test counter.", 3)
+		w.Gauge("kermoo_test_gauge", "A test gauge.", 2, metrics.Label{Name: "name", Value: "b"})
+
+		sb := strings.Builder{}
+		_, err := w.WriteTo(&sb)
+		require.NoError(t, err)
+
+		assert.Equal(t, strings.Join([]string{
+			"# HELP kermoo_test_gauge A test gauge.",
+			"# TYPE kermoo_test_gauge gauge",
+			`kermoo_test_gauge{name="a"} 1.5`,
+			`kermoo_test_gauge{name="b"} 2`,
+			"# HELP kermoo_test_counter A test counter.",
+			"# TYPE kermoo_test_counter counter",
+			"kermoo_test_counter 3",
+			"",
+		}, "\n"), sb.String())
+	})
+
+	t.Run("escapes label values", func(t *testing.T) {
+		w := metrics.NewWriter()
+		w.Gauge("kermoo_test_gauge", "A test gauge.", 1, metrics.Label{Name: "name", Value: "a \"quoted\" \\ value\n"})
+
+		sb := strings.Builder{}
+		_, _ = w.WriteTo(&sb)
+
+		assert.Contains(t, sb.String(), `kermoo_test_gauge{name="a \"quoted\" \\ value\n"} 1`)
+	})
+}
+
+var counter = metrics.NewCounterVec("kermoo_test_total", "A test counter.", "kind")
+
+func TestCounterVec(t *testing.T) {
+	if counter.Get("a") > 0 {
+		t.Skip("counter is already increased by a previous run")
+	}
+
+	assert.NotContains(t, gather(), "kermoo_test_total")
+
+	counter.Inc("a")
+	counter.Inc("a")
+	counter.Inc("b")
+
+	assert.Equal(t, float64(2), counter.Get("a"))
+	assert.Equal(t, float64(1), counter.Get("b"))
+	assert.Equal(t, float64(0), counter.Get("c"))
+
+	content := gather()
+	assert.Contains(t, content, "# TYPE kermoo_test_total counter\n")
+	assert.Contains(t, content, `kermoo_test_total{kind="a"} 2`)
+	assert.Contains(t, content, `kermoo_test_total{kind="b"} 1`)
+}
+
+func TestModuleMetrics(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	t.Run("exposes plan values", func(t *testing.T) {
+		plan := planner.NewPlan(planner.Plan{
+			Name:       utils.NewP[string]("disaster"),
+			Percentage: fluent.NewMustFluentFloat("40"),
+			Size:       fluent.NewMustFluentSize("1Ki"),
+			Latency:    fluent.NewMustFluentDuration("250ms"),
+			Interval:   fluent.NewMustFluentDuration("1h"),
+		})
+		t.Cleanup(plan.Stop)
+
+		content := gather(&plan)
+		assert.Contains(t, content, `kermoo_plan_running{plan="disaster"} 0`)
+		assert.NotContains(t, content, "kermoo_plan_percentage")
+
+		plan.SetCurrentValue(planner.CycleValue{Percentage: 40, Size: 1024, Latency: plan.Latency.Get()})
+
+		content = gather(&plan)
+		assert.Contains(t, content, `kermoo_plan_percentage{plan="disaster"} 40`)
+		assert.Contains(t, content, `kermoo_plan_size_bytes{plan="disaster"} 1024`)
+		assert.Contains(t, content, `kermoo_plan_latency_seconds{plan="disaster"} 0.25`)
+		assert.Contains(t, content, `kermoo_plan_cycle_index{plan="disaster"} 0`)
+	})
+
+	t.Run("exposes leaked bytes", func(t *testing.T) {
+		leak := memory.MemoryLeak{}
+
+		leak.StartLeaking(2048)
+		assert.Contains(t, gather(&leak), "kermoo_memory_leaked_bytes 2048")
+
+		leak.StopLeaking()
+		assert.Contains(t, gather(&leak), "kermoo_memory_leaked_bytes 0")
+	})
+
+	t.Run("exposes web server state", func(t *testing.T) {
+		ws := web_server.WebServer{Port: utils.NewP[int32](8103)}
+
+		assert.Contains(t, gather(&ws), `kermoo_webserver_up{webserver="webserver-0-0-0-0-8103",address="0.0.0.0:8103"} 0`)
+	})
+
+	t.Run("counts injected route faults", func(t *testing.T) {
+		path := fmt.Sprintf("/always-failing-%d", time.Now().UnixNano())
+		route := web_server.Route{
+			Path: path,
+			Fault: &web_server.RouteFault{
+				Percentage: *fluent.NewMustFluentFloat("100"),
+			},
+		}
+
+		ws := web_server.WebServer{Port: utils.NewP[int32](8104), Routes: []*web_server.Route{&route}}
+		require.NoError(t, ws.Validate())
+
+		plan := route.MakeInlinePlan()
+		plan.Assign(&route)
+		plan.SetCurrentValue(planner.CycleValue{Percentage: 100, ComputedPercentageChance: utils.NewP[bool](false)})
+
+		codes := map[int]int{}
+		for i := 0; i < 10; i++ {
+			w := httptest.NewRecorder()
+			route.Handle(w, httptest.NewRequest(http.MethodGet, path, nil))
+			codes[w.Code]++
+		}
+
+		content := gather()
+		for code, count := range codes {
+			assert.Contains(t, content, fmt.Sprintf(`kermoo_route_faults_total{webserver="webserver-0-0-0-0-8104",route="%s",code="%d"} %d`, path, code, count))
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package planner_test
+
+import (
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/planner"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowPlannable counts the calls of its preSleep hook, which takes a while to return.
+type slowPlannable struct {
+	planner.CanAssignPlan
+	calls   atomic.Int32
+	running atomic.Bool
+}
+
+func (s *slowPlannable) GetName() string                { return "slow" }
+func (s *slowPlannable) GetDesiredPlanNames() []string  { return nil }
+func (s *slowPlannable) HasInlinePlan() bool            { return false }
+func (s *slowPlannable) MakeInlinePlan() *planner.Plan  { return nil }
+func (s *slowPlannable) MakeDefaultPlan() *planner.Plan { return nil }
+
+func (s *slowPlannable) GetPlanCycleHooks() planner.CycleHooks {
+	preSleep := planner.HookFunc(func(cycle planner.Cycle) planner.PlanSignal {
+		s.running.Store(true)
+		time.Sleep(50 * time.Millisecond)
+		s.calls.Add(1)
+		s.running.Store(false)
+
+		return planner.PLAN_SIGNAL_CONTINUE
+	})
+
+	return planner.CycleHooks{PreSleep: &preSleep}
+}
+
+func TestPlanAssignment(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	t.Run("unassign waits for the hook in flight", func(t *testing.T) {
+		plan := planner.NewPlan(planner.Plan{
+			Percentage: fluent.NewMustFluentFloat("0"),
+			Interval:   fluent.NewMustFluentDuration("10ms"),
+			Name:       &name,
+		})
+
+		slow := &slowPlannable{}
+		plan.Assign(slow)
+
+		go plan.Start()
+		defer plan.Stop()
+
+		assert.Eventually(t, slow.running.Load, time.Second, time.Millisecond)
+
+		plan.Unassign(slow)
+		assert.False(t, slow.running.Load())
+
+		calls := slow.calls.Load()
+		time.Sleep(100 * time.Millisecond)
+		assert.Equal(t, calls, slow.calls.Load(), "no hook should run after unassigning")
+	})
+
+	t.Run("replays the last cycle of a finished plan", func(t *testing.T) {
+		plan := planner.NewPlan(planner.Plan{
+			Percentage: fluent.NewMustFluentFloat("0"),
+			Interval:   fluent.NewMustFluentDuration("10ms"),
+			Duration:   fluent.NewMustFluentDuration("10ms"),
+			Name:       &name,
+		})
+
+		plan.Start()
+
+		slow := &slowPlannable{}
+		plan.Assign(slow)
+		plan.ReplayLastCycle(slow)
+
+		assert.Equal(t, int32(1), slow.calls.Load())
+	})
+}
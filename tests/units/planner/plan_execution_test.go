@@ -108,6 +108,44 @@ func TestSimplePlanExecution(t *testing.T) {
 		})
 	})
 
+	t.Run("executes simple plan with latency chart bar", func(t *testing.T) {
+		defer teardownSubTest(t)
+
+		plan := planner.NewPlan(planner.Plan{
+			Interval: fluent.NewMustFluentDuration("10ms"),
+			Duration: fluent.NewMustFluentDuration("50ms"),
+			Name:     &name,
+		})
+
+		plan.Latency = fluent.NewMustFluentDuration("10ms, 50ms, 2s, 10ms")
+
+		plan.Assign(&Recorder)
+
+		require.NoError(t, plan.Validate())
+
+		plan.Start()
+
+		Recorder.AssertTotalTimeSpent(t, 50*time.Millisecond, acceptedError)
+
+		Recorder.AssertCycleValues(t, []ExpectedCycleValue{
+			{Latency: fluent.NewMustFluentDuration("10ms")},
+			{Latency: fluent.NewMustFluentDuration("50ms")},
+			{Latency: fluent.NewMustFluentDuration("2s")},
+			{Latency: fluent.NewMustFluentDuration("10ms")},
+			{Latency: fluent.NewMustFluentDuration("10ms")},
+		})
+	})
+
+	t.Run("fails when latency count does not match the percentage count", func(t *testing.T) {
+		plan := planner.NewPlan(planner.Plan{
+			Name:       &name,
+			Percentage: fluent.NewMustFluentFloat("10, 20"),
+			Latency:    fluent.NewMustFluentDuration("10ms, 50ms, 2s"),
+		})
+
+		require.Error(t, plan.Validate())
+	})
+
 	t.Run("simple plan without duration lasts for ever", func(t *testing.T) {
 		t.Skip("TODO: Implement")
 	})
@@ -20,6 +20,7 @@ type PlanRecorder struct {
 type ExpectedCycleValue struct {
 	Percentage *fluent.FluentFloat
 	Size       *fluent.FluentSize
+	Latency    *fluent.FluentDuration
 }
 
 func (r *PlanRecorder) Reset() {
@@ -62,6 +63,22 @@ func (r *PlanRecorder) AssertCycleValues(t *testing.T, expectedCycleValues []Exp
 			}
 
 		}
+
+		if ev.Latency != nil {
+			actual := r.Cycles[i].Value.Latency
+
+			evpv := ev.Latency.GetParsedValue()
+
+			if evpv.IsRanged() {
+				min, max, _ := ev.Latency.GetParsedValue().GetRange()
+				assert.LessOrEqual(t, min, actual)
+				assert.GreaterOrEqual(t, max, actual)
+
+			} else {
+				assert.Equal(t, ev.Latency.Get(), actual)
+			}
+
+		}
 	}
 }
 
@@ -0,0 +1,95 @@
+package process_test
+
+import (
+	"kermoo/modules/fluent"
+	"kermoo/modules/process"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessCrash_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		crash   *process.ProcessCrash
+		wantErr bool
+	}{
+		{
+			name:  "valid with percentage",
+			crash: &process.ProcessCrash{Percentage: *fluent.NewMustFluentFloat("10")},
+		},
+		{
+			name:  "valid with plan refs",
+			crash: &process.ProcessCrash{PlanRefs: []string{"crash"}},
+		},
+		{
+			name: "valid with types and exit codes",
+			crash: &process.ProcessCrash{
+				Percentage: *fluent.NewMustFluentFloat("10"),
+				Types:      []string{process.CRASH_TYPE_EXIT, process.CRASH_TYPE_SEGFAULT},
+				ExitCodes:  []uint{1, 137},
+			},
+		},
+		{
+			name:    "invalid with neither percentage nor plan refs",
+			crash:   &process.ProcessCrash{},
+			wantErr: true,
+		},
+		{
+			name: "invalid with unknown type",
+			crash: &process.ProcessCrash{
+				Percentage: *fluent.NewMustFluentFloat("10"),
+				Types:      []string{"explode"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid with out of range exit code",
+			crash: &process.ProcessCrash{
+				Percentage: *fluent.NewMustFluentFloat("10"),
+				ExitCodes:  []uint{256},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.crash.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestProcessCrash_MakeInlinePlan(t *testing.T) {
+	crash := &process.ProcessCrash{
+		Percentage: *fluent.NewMustFluentFloat("25"),
+		Interval:   fluent.NewMustFluentDuration("1m"),
+	}
+
+	plan := crash.MakeInlinePlan()
+
+	require.NotNil(t, plan)
+	assert.Equal(t, float64(25), plan.Percentage.Get())
+	assert.Equal(t, time.Minute, plan.Interval.Get())
+
+	assert.Nil(t, (&process.ProcessCrash{PlanRefs: []string{"crash"}}).MakeInlinePlan())
+}
+
+func TestProcessCrash_Picks(t *testing.T) {
+	crash := &process.ProcessCrash{}
+
+	assert.Len(t, crash.GetTypes(), 5)
+	assert.Equal(t, 1, crash.PickExitCode())
+
+	crash.ExitCodes = []uint{3, 4}
+	for i := 0; i < 10; i++ {
+		assert.Contains(t, []int{3, 4}, crash.PickExitCode())
+	}
+}
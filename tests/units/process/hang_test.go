@@ -0,0 +1,97 @@
+package process_test
+
+import (
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/process"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessHang(t *testing.T) {
+	logger.MustInitLogger("fatal")
+	t.Cleanup(process.Release)
+
+	t.Run("holds the waiters until released", func(t *testing.T) {
+		process.Hang()
+		assert.True(t, process.IsHung())
+
+		released := make(chan struct{})
+
+		go func() {
+			process.WaitWhileHung()
+			close(released)
+		}()
+
+		select {
+		case <-released:
+			t.Fatal("waiter should be held while hung")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		process.Release()
+		assert.False(t, process.IsHung())
+
+		select {
+		case <-released:
+		case <-time.After(time.Second):
+			t.Fatal("waiter should be released")
+		}
+	})
+
+	t.Run("holds the web server requests", func(t *testing.T) {
+		ws := &web_server.WebServer{
+			Interface: utils.NewP[string]("127.0.0.1"),
+			Port:      utils.NewP[int32](8501),
+		}
+
+		require.NoError(t, ws.ListenOnBackground())
+		t.Cleanup(func() { ws.Stop() })
+
+		// Give server a moment to start
+		time.Sleep(100 * time.Millisecond)
+
+		process.Hang()
+
+		_, err := (&http.Client{Timeout: 200 * time.Millisecond}).Get("http://127.0.0.1:8501/")
+		assert.Error(t, err, "request should be held while hung")
+
+		process.Release()
+
+		resp, err := http.Get("http://127.0.0.1:8501/")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("hangs on failing cycles of plan", func(t *testing.T) {
+		hang := &process.ProcessHang{
+			Percentage: *fluent.NewMustFluentFloat("100"),
+			Interval:   fluent.NewMustFluentDuration("50ms"),
+		}
+		require.NoError(t, hang.Validate())
+
+		plan := hang.MakeInlinePlan()
+		plan.Name = utils.NewP[string]("hang")
+		plan.Assign(hang)
+
+		go plan.Start()
+
+		assert.Eventually(t, process.IsHung, time.Second, 10*time.Millisecond)
+
+		plan.Stop()
+		process.Release()
+	})
+
+	t.Run("validates", func(t *testing.T) {
+		assert.Error(t, (&process.ProcessHang{}).Validate())
+		assert.NoError(t, (&process.ProcessHang{PlanRefs: []string{"hang"}}).Validate())
+	})
+}
@@ -42,6 +42,23 @@ func TestProcess_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid with shutdown",
+			process: process.Process{
+				Shutdown: &process.ProcessShutdown{
+					GracePeriod: fluent.NewMustFluentDuration("5s"),
+					ExitCode:    143,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid with out of range shutdown exit code",
+			process: process.Process{
+				Shutdown: &process.ProcessShutdown{ExitCode: 256},
+			},
+			wantErr: true,
+		},
 		// {
 		// 	name: "invalid with bad delay duration (between with single value)",
 		// 	process: process.Process{
@@ -66,6 +83,8 @@ func TestProcess_Validate(t *testing.T) {
 			err := tt.process.Validate()
 			if tt.wantErr {
 				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
 			}
 		})
 	}
@@ -0,0 +1,32 @@
+package process_test
+
+import (
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/process"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessStartup_Validate(t *testing.T) {
+	code := uint(256)
+
+	require.NoError(t, (&process.ProcessStartup{Duration: fluent.NewMustFluentDuration("1s")}).Validate())
+	require.Error(t, (&process.ProcessStartup{ExitCode: &code}).Validate())
+	require.Error(t, (&process.ProcessStartup{FailurePercentage: fluent.NewMustFluentFloat("120")}).Validate())
+}
+
+func TestProcessStartup_Begin(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	ps := process.ProcessStartup{Duration: fluent.NewMustFluentDuration("200ms")}
+
+	ps.Begin()
+	assert.True(t, process.IsInitializing())
+
+	time.Sleep(400 * time.Millisecond)
+	assert.False(t, process.IsInitializing())
+}
@@ -0,0 +1,195 @@
+package tcp_server_test
+
+import (
+	"errors"
+	"io"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/tcp_server"
+	"kermoo/modules/utils"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T, ts *tcp_server.TcpServer, fault string) {
+	require.NoError(t, ts.SetFault(fault))
+	t.Cleanup(ts.Stop)
+}
+
+func dial(t *testing.T, ts *tcp_server.TcpServer) net.Conn {
+	conn, err := net.Dial("tcp", ts.GetAddress())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	require.NoError(t, conn.SetDeadline(time.Now().Add(300*time.Millisecond)))
+
+	return conn
+}
+
+// readAll reads the connection until it's closed. The error of dialing is returned too,
+// since a reset can happen before the connection is fully established.
+func readAll(ts *tcp_server.TcpServer) ([]byte, error) {
+	conn, err := net.Dial("tcp", ts.GetAddress())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(300 * time.Millisecond)); err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(conn)
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("requires port", func(t *testing.T) {
+		err := (&tcp_server.TcpServer{}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "port")
+	})
+
+	t.Run("rejects unknown fault type", func(t *testing.T) {
+		err := (&tcp_server.TcpServer{
+			Port:  utils.NewP[int32](8201),
+			Fault: &tcp_server.TcpServerFault{Types: []string{"explode"}},
+		}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "explode")
+	})
+
+	t.Run("rejects negative close after bytes", func(t *testing.T) {
+		err := (&tcp_server.TcpServer{
+			Port:  utils.NewP[int32](8201),
+			Fault: &tcp_server.TcpServerFault{CloseAfterBytes: utils.NewP[int64](-1)},
+		}).Validate()
+		require.Error(t, err)
+	})
+}
+
+func TestTcpServer(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	t.Run("echoes", func(t *testing.T) {
+		ts := &tcp_server.TcpServer{Interface: utils.NewP[string]("127.0.0.1"), Port: utils.NewP[int32](8201)}
+		startServer(t, ts, "")
+
+		conn := dial(t, ts)
+		_, err := conn.Write([]byte("ping"))
+		require.NoError(t, err)
+
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+		assert.Equal(t, "ping", string(buf))
+	})
+
+	t.Run("sends banner and closes", func(t *testing.T) {
+		ts := &tcp_server.TcpServer{
+			Interface: utils.NewP[string]("127.0.0.1"),
+			Port:      utils.NewP[int32](8202),
+			Content:   tcp_server.TcpContent{Banner: "SSH-2.0-Kermoo\r\n", Echo: utils.NewP[bool](false)},
+		}
+		startServer(t, ts, "")
+
+		content, err := io.ReadAll(dial(t, ts))
+		require.NoError(t, err)
+		assert.Equal(t, "SSH-2.0-Kermoo\r\n", string(content))
+	})
+
+	t.Run("resets connections", func(t *testing.T) {
+		ts := &tcp_server.TcpServer{Interface: utils.NewP[string]("127.0.0.1"), Port: utils.NewP[int32](8203)}
+		startServer(t, ts, tcp_server.TCP_FAULT_RESET)
+
+		_, err := readAll(ts)
+		assert.True(t, errors.Is(err, syscall.ECONNRESET), "expected connection reset but got %v", err)
+	})
+
+	t.Run("hangs connections", func(t *testing.T) {
+		ts := &tcp_server.TcpServer{Interface: utils.NewP[string]("127.0.0.1"), Port: utils.NewP[int32](8204)}
+		startServer(t, ts, tcp_server.TCP_FAULT_HANG)
+
+		conn := dial(t, ts)
+		_, err := conn.Write([]byte("ping"))
+		require.NoError(t, err)
+
+		_, err = conn.Read(make([]byte, 4))
+		assert.True(t, errors.Is(err, os.ErrDeadlineExceeded), "expected timeout but got %v", err)
+	})
+
+	t.Run("closes after n bytes", func(t *testing.T) {
+		ts := &tcp_server.TcpServer{
+			Interface: utils.NewP[string]("127.0.0.1"),
+			Port:      utils.NewP[int32](8205),
+			Content:   tcp_server.TcpContent{Banner: "hello world"},
+			Fault:     &tcp_server.TcpServerFault{CloseAfterBytes: utils.NewP[int64](5)},
+		}
+		startServer(t, ts, tcp_server.TCP_FAULT_CLOSE)
+
+		content, err := io.ReadAll(dial(t, ts))
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(content))
+	})
+
+	t.Run("closes right away with zero bytes", func(t *testing.T) {
+		ts := &tcp_server.TcpServer{
+			Interface: utils.NewP[string]("127.0.0.1"),
+			Port:      utils.NewP[int32](8208),
+			Fault:     &tcp_server.TcpServerFault{},
+		}
+		startServer(t, ts, tcp_server.TCP_FAULT_CLOSE)
+
+		// Nothing is sent, so the connection should be closed regardless of the client
+		_, err := dial(t, ts).Read(make([]byte, 4))
+		assert.ErrorIs(t, err, io.EOF)
+	})
+
+	t.Run("refuses connections", func(t *testing.T) {
+		ts := &tcp_server.TcpServer{Interface: utils.NewP[string]("127.0.0.1"), Port: utils.NewP[int32](8206)}
+		startServer(t, ts, "")
+		assert.True(t, ts.IsListening())
+
+		require.NoError(t, ts.SetFault(tcp_server.TCP_FAULT_REFUSE))
+		assert.False(t, ts.IsListening())
+
+		_, err := net.Dial("tcp", ts.GetAddress())
+		assert.True(t, errors.Is(err, syscall.ECONNREFUSED), "expected connection refused but got %v", err)
+
+		require.NoError(t, ts.SetFault(""))
+		assert.True(t, ts.IsListening(), "server should listen again once healthy")
+	})
+}
+
+func TestTcpServerPlan(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	ts := &tcp_server.TcpServer{
+		Interface: utils.NewP[string]("127.0.0.1"),
+		Port:      utils.NewP[int32](8207),
+		Fault: &tcp_server.TcpServerFault{
+			Percentage: *fluent.NewMustFluentFloat("100"),
+			Types:      []string{tcp_server.TCP_FAULT_RESET},
+		},
+	}
+	require.NoError(t, ts.Validate())
+	t.Cleanup(ts.Stop)
+
+	plan := ts.MakeInlinePlan()
+	plan.Name = utils.NewP[string]("tcp")
+	plan.Assign(ts)
+	t.Cleanup(plan.Stop)
+
+	go plan.Start()
+
+	require.Eventually(t, ts.IsListening, time.Second, 5*time.Millisecond)
+	assert.Equal(t, tcp_server.TCP_FAULT_RESET, ts.GetFault())
+
+	_, err := readAll(ts)
+	assert.True(t, errors.Is(err, syscall.ECONNRESET), "expected connection reset but got %v", err)
+}
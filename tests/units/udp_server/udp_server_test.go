@@ -0,0 +1,194 @@
+package udp_server_test
+
+import (
+	"errors"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/udp_server"
+	"kermoo/modules/utils"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T, us *udp_server.UdpServer, percentage float64) {
+	require.NoError(t, us.Listen())
+	us.SetPercentages(percentage)
+	t.Cleanup(us.Stop)
+}
+
+func dial(t *testing.T, us *udp_server.UdpServer) net.Conn {
+	conn, err := net.Dial("udp", us.GetAddress())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func send(t *testing.T, conn net.Conn, payload string) {
+	_, err := conn.Write([]byte(payload))
+	require.NoError(t, err)
+}
+
+// receive returns the next datagram or an empty string if nothing is received within
+// the timeout.
+func receive(t *testing.T, conn net.Conn, timeout time.Duration) string {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(timeout)))
+
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return ""
+	}
+	require.NoError(t, err)
+
+	return string(buf[:n])
+}
+
+func newServer(port int32, types ...string) *udp_server.UdpServer {
+	return &udp_server.UdpServer{
+		Interface: utils.NewP[string]("127.0.0.1"),
+		Port:      utils.NewP[int32](port),
+		Fault: &udp_server.UdpServerFault{
+			Types: types,
+			Delay: fluent.NewMustFluentDuration("200ms"),
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("requires port", func(t *testing.T) {
+		err := (&udp_server.UdpServer{}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "port")
+	})
+
+	t.Run("rejects unknown fault type", func(t *testing.T) {
+		err := newServer(8301, "explode").Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "explode")
+	})
+
+	t.Run("rejects negative truncate bytes", func(t *testing.T) {
+		us := newServer(8301)
+		us.Fault.TruncateBytes = utils.NewP[int64](-1)
+		require.Error(t, us.Validate())
+	})
+}
+
+func TestUdpServer(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	t.Run("echoes", func(t *testing.T) {
+		us := newServer(8302)
+		startServer(t, us, 0)
+
+		conn := dial(t, us)
+		send(t, conn, "ping")
+		assert.Equal(t, "ping", receive(t, conn, time.Second))
+	})
+
+	t.Run("drops", func(t *testing.T) {
+		us := newServer(8303, udp_server.UDP_FAULT_DROP)
+		startServer(t, us, 100)
+
+		conn := dial(t, us)
+		send(t, conn, "ping")
+		assert.Equal(t, "", receive(t, conn, 200*time.Millisecond))
+	})
+
+	t.Run("delays", func(t *testing.T) {
+		us := newServer(8304, udp_server.UDP_FAULT_DELAY)
+		startServer(t, us, 100)
+
+		conn := dial(t, us)
+		send(t, conn, "ping")
+		assert.Equal(t, "", receive(t, conn, 100*time.Millisecond))
+		assert.Equal(t, "ping", receive(t, conn, time.Second))
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		us := newServer(8305, udp_server.UDP_FAULT_DUPLICATE)
+		startServer(t, us, 100)
+
+		conn := dial(t, us)
+		send(t, conn, "ping")
+		assert.Equal(t, "ping", receive(t, conn, time.Second))
+		assert.Equal(t, "ping", receive(t, conn, time.Second))
+	})
+
+	t.Run("reorders", func(t *testing.T) {
+		us := newServer(8306, udp_server.UDP_FAULT_REORDER)
+		startServer(t, us, 100)
+
+		conn := dial(t, us)
+		send(t, conn, "first")
+		assert.Equal(t, "", receive(t, conn, 100*time.Millisecond))
+
+		us.SetPercentages(0)
+		send(t, conn, "second")
+		assert.Equal(t, "second", receive(t, conn, time.Second))
+		assert.Equal(t, "first", receive(t, conn, time.Second))
+	})
+
+	t.Run("truncates", func(t *testing.T) {
+		us := newServer(8307, udp_server.UDP_FAULT_TRUNCATE)
+		startServer(t, us, 100)
+
+		conn := dial(t, us)
+		send(t, conn, "pingpong")
+		assert.Equal(t, "ping", receive(t, conn, time.Second))
+
+	})
+
+	t.Run("truncates to given bytes", func(t *testing.T) {
+		us := newServer(8310, udp_server.UDP_FAULT_TRUNCATE)
+		us.Fault.TruncateBytes = utils.NewP[int64](2)
+		startServer(t, us, 100)
+
+		conn := dial(t, us)
+		send(t, conn, "pingpong")
+		assert.Equal(t, "pi", receive(t, conn, time.Second))
+	})
+
+	t.Run("listens again after stop", func(t *testing.T) {
+		us := newServer(8308)
+		startServer(t, us, 0)
+
+		us.Stop()
+		assert.False(t, us.IsListening())
+
+		require.NoError(t, us.Listen())
+		assert.True(t, us.IsListening())
+
+		conn := dial(t, us)
+		send(t, conn, "ping")
+		assert.Equal(t, "ping", receive(t, conn, time.Second))
+	})
+}
+
+func TestUdpServerPlan(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	us := newServer(8309, udp_server.UDP_FAULT_DROP)
+	us.Fault.Percentage = *fluent.NewMustFluentFloat("100")
+	require.NoError(t, us.Validate())
+	t.Cleanup(us.Stop)
+
+	plan := us.MakeInlinePlan()
+	plan.Name = utils.NewP[string]("udp")
+	plan.Assign(us)
+	t.Cleanup(plan.Stop)
+
+	go plan.Start()
+
+	require.Eventually(t, us.IsListening, time.Second, 5*time.Millisecond)
+
+	conn := dial(t, us)
+	send(t, conn, "ping")
+	assert.Equal(t, "", receive(t, conn, 200*time.Millisecond))
+}
@@ -0,0 +1,263 @@
+package user_config_test
+
+import (
+	"io"
+	"kermoo/modules/logger"
+	"kermoo/modules/user_config"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getBody(t *testing.T, url string) string {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+func TestReload(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	prepared, err := user_config.MakePreparedConfig(`
+plans:
+- name: shared
+  percentage: 0
+  interval: 10ms
+webServers:
+- port: 8101
+  interface: 127.0.0.1
+  routes:
+  - path: /kept
+    content:
+      static: kept
+    fault:
+      planRefs: [shared]
+- port: 8102
+  interface: 127.0.0.1
+  routes:
+  - path: /changed
+    content:
+      static: before
+    fault:
+      planRefs: [shared]
+memoryLeak:
+  size: 1Ki
+  interval: 10ms
+`)
+	require.NoError(t, err)
+
+	prepared.Start()
+	defer func() {
+		for _, plan := range prepared.Plans {
+			plan.Stop()
+		}
+		for _, ws := range prepared.WebServers {
+			ws.Stop()
+		}
+
+		// Give webservers a moment to go down
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, "kept", getBody(t, "http://127.0.0.1:8101/kept"))
+	assert.Equal(t, "before", getBody(t, "http://127.0.0.1:8102/changed"))
+
+	keptWebServer := prepared.WebServers[0]
+	sharedPlan := prepared.Plans[0]
+
+	t.Run("fails on invalid config and keeps the current one", func(t *testing.T) {
+		next, err := user_config.MakePreparedConfig(`
+webServers:
+- port: 8101
+- port: 8101
+`)
+		require.NoError(t, err)
+
+		require.Error(t, prepared.Reload(next))
+		assert.Same(t, keptWebServer, prepared.WebServers[0])
+	})
+
+	t.Run("keeps unchanged components and restarts the changed ones", func(t *testing.T) {
+		next, err := user_config.MakePreparedConfig(`
+plans:
+- name: shared
+  percentage: 0
+  interval: 10ms
+webServers:
+- port: 8101
+  interface: 127.0.0.1
+  routes:
+  - path: /kept
+    content:
+      static: kept
+    fault:
+      planRefs: [shared]
+- port: 8102
+  interface: 127.0.0.1
+  routes:
+  - path: /changed
+    content:
+      static: after
+    fault:
+      planRefs: [shared]
+`)
+		require.NoError(t, err)
+
+		require.NoError(t, prepared.Reload(next))
+
+		time.Sleep(100 * time.Millisecond)
+
+		assert.Same(t, keptWebServer, prepared.WebServers[0])
+		assert.Same(t, sharedPlan, prepared.Plans[0])
+		assert.Nil(t, prepared.MemoryLeak)
+		assert.Len(t, sharedPlan.GetPlannables(), 2)
+
+		for _, plan := range prepared.Plans {
+			assert.NotEqual(t, "memory-leaker-custom-plan", *plan.Name, "dedicated plan of the removed memory leak should be gone")
+		}
+
+		assert.Equal(t, "kept", getBody(t, "http://127.0.0.1:8101/kept"))
+		assert.Equal(t, "after", getBody(t, "http://127.0.0.1:8102/changed"))
+	})
+
+	t.Run("restarts dependent components when a shared plan is changed", func(t *testing.T) {
+		next, err := user_config.MakePreparedConfig(`
+plans:
+- name: shared
+  percentage: 100
+  interval: 10ms
+webServers:
+- port: 8101
+  interface: 127.0.0.1
+  routes:
+  - path: /kept
+    content:
+      static: kept
+    fault:
+      planRefs: [shared]
+`)
+		require.NoError(t, err)
+
+		require.NoError(t, prepared.Reload(next))
+
+		time.Sleep(100 * time.Millisecond)
+
+		assert.NotSame(t, keptWebServer, prepared.WebServers[0])
+		assert.NotSame(t, sharedPlan, prepared.Plans[0])
+		assert.Equal(t, "finished", sharedPlan.GetState())
+
+		resp, err := http.Get("http://127.0.0.1:8101/kept")
+		require.NoError(t, err)
+		assert.Equal(t, 5, resp.StatusCode/100)
+
+		_, err = http.Get("http://127.0.0.1:8102/changed")
+		assert.Error(t, err, "removed webserver should be stopped")
+	})
+}
+
+func TestReloadStopsModules(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	prepared, err := user_config.MakePreparedConfig(`
+cpuLoad:
+  percentage: 1
+  interval: 10ms
+memoryLeak:
+  size: 1Ki
+  interval: 10ms
+`)
+	require.NoError(t, err)
+
+	prepared.Start()
+
+	cpuLoad := prepared.CpuLoad
+	memoryLeak := prepared.MemoryLeak
+	require.NotNil(t, cpuLoad)
+	require.NotNil(t, memoryLeak)
+
+	assert.Eventually(t, func() bool {
+		ctx, _ := cpuLoad.GetContextAndCancel()
+		return ctx != nil && len(memoryLeak.GetLeakedData()) > 0
+	}, time.Second, 5*time.Millisecond)
+
+	next, err := user_config.MakePreparedConfig("schemaVersion: \"1\"\n")
+	require.NoError(t, err)
+
+	require.NoError(t, prepared.Reload(next))
+
+	assert.Nil(t, prepared.CpuLoad)
+	assert.Nil(t, prepared.MemoryLeak)
+	assert.Empty(t, prepared.Plans)
+
+	// Modules should stay stopped, rather than being revived by their plans
+	for i := 0; i < 5; i++ {
+		ctx, _ := cpuLoad.GetContextAndCancel()
+		assert.Error(t, ctx.Err())
+		assert.Empty(t, memoryLeak.GetLeakedData())
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloadOnFinishedPlan(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	config := `
+plans:
+- name: short
+  percentage: 0
+  interval: 10ms
+  duration: 20ms
+webServers:
+- port: 8106
+  interface: 127.0.0.1
+  fault:
+    planRefs: [short]
+`
+
+	prepared, err := user_config.MakePreparedConfig(config)
+	require.NoError(t, err)
+
+	prepared.Start()
+	defer func() {
+		for _, ws := range prepared.WebServers {
+			ws.Stop()
+		}
+	}()
+
+	plan := prepared.Plans[0]
+	assert.Eventually(t, func() bool {
+		return plan.GetState() == "finished"
+	}, time.Second, 5*time.Millisecond)
+
+	next, err := user_config.MakePreparedConfig(config + `
+- port: 8107
+  interface: 127.0.0.1
+  fault:
+    planRefs: [short]
+`)
+	require.NoError(t, err)
+
+	require.NoError(t, prepared.Reload(next))
+
+	assert.Same(t, plan, prepared.Plans[0])
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", "127.0.0.1:8107")
+		if err != nil {
+			return false
+		}
+		conn.Close()
+
+		return true
+	}, time.Second, 5*time.Millisecond, "webserver on a finished plan should be started")
+}
@@ -0,0 +1,164 @@
+package user_config_test
+
+import (
+	"kermoo/modules/logger"
+	"kermoo/modules/user_config"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	t.Run("drains in-flight requests", func(t *testing.T) {
+		prepared, err := user_config.MakePreparedConfig(`
+process:
+  shutdown:
+    exitCode: 3
+webServers:
+- port: 8103
+  interface: 127.0.0.1
+  routes:
+  - path: /slow
+    fault:
+      responseDelay: 300ms
+`)
+		require.NoError(t, err)
+
+		prepared.Start()
+
+		// Give webserver a moment to start
+		time.Sleep(100 * time.Millisecond)
+
+		status := make(chan int, 1)
+
+		go func() {
+			resp, err := http.Get("http://127.0.0.1:8103/slow")
+			if err != nil {
+				status <- 0
+				return
+			}
+			resp.Body.Close()
+
+			status <- resp.StatusCode
+		}()
+
+		// Let the request get in
+		time.Sleep(100 * time.Millisecond)
+
+		assert.Equal(t, 3, prepared.Shutdown())
+		assert.Equal(t, http.StatusOK, <-status)
+		assert.False(t, prepared.WebServers[0].IsListening())
+	})
+
+	t.Run("closes the remaining connections after grace period", func(t *testing.T) {
+		prepared, err := user_config.MakePreparedConfig(`
+process:
+  shutdown:
+    gracePeriod: 100ms
+webServers:
+- port: 8104
+  interface: 127.0.0.1
+  routes:
+  - path: /slow
+    fault:
+      responseDelay: 2s
+`)
+		require.NoError(t, err)
+
+		prepared.Start()
+
+		// Give webserver a moment to start
+		time.Sleep(100 * time.Millisecond)
+
+		failed := make(chan error, 1)
+
+		go func() {
+			resp, err := http.Get("http://127.0.0.1:8104/slow")
+			if err == nil {
+				resp.Body.Close()
+			}
+
+			failed <- err
+		}()
+
+		// Let the request get in
+		time.Sleep(100 * time.Millisecond)
+
+		start := time.Now()
+		assert.Equal(t, 0, prepared.Shutdown())
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Error(t, <-failed)
+	})
+
+	t.Run("keeps serving and delays the exit", func(t *testing.T) {
+		prepared, err := user_config.MakePreparedConfig(`
+process:
+  shutdown:
+    keepServing: 300ms
+    exitDelay: 200ms
+webServers:
+- port: 8105
+  interface: 127.0.0.1
+`)
+		require.NoError(t, err)
+
+		prepared.Start()
+
+		// Give webserver a moment to start
+		time.Sleep(100 * time.Millisecond)
+
+		done := make(chan int, 1)
+		start := time.Now()
+
+		go func() { done <- prepared.Shutdown() }()
+
+		time.Sleep(100 * time.Millisecond)
+
+		resp, err := http.Get("http://127.0.0.1:8105/")
+		require.NoError(t, err, "new traffic should still be accepted")
+		resp.Body.Close()
+
+		<-done
+		assert.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond)
+	})
+}
+
+func TestNotifyShutdownSignals(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	prepared, err := user_config.MakePreparedConfig(`
+process:
+  delay: 300ms
+`)
+	require.NoError(t, err)
+
+	signals := user_config.NotifyShutdownSignals()
+	t.Cleanup(func() { signal.Reset(syscall.SIGTERM, syscall.SIGINT) })
+
+	started := make(chan struct{})
+
+	go func() {
+		prepared.Start()
+		close(started)
+	}()
+
+	// Signal during the initial delay should be caught rather than killing the process
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGTERM))
+
+	select {
+	case sig := <-signals:
+		assert.Equal(t, syscall.SIGTERM, sig)
+	case <-time.After(time.Second):
+		t.Fatal("signal is not caught")
+	}
+
+	<-started
+}
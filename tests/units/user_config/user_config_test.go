@@ -180,6 +180,11 @@ func TestMakeConfigFromString(t *testing.T) {
 			content:      getFileContent(t, PATH_INVALSTRUCTURE_YAML),
 			expectsError: true,
 		},
+		{
+			name:         "grpc server with unknown fault type",
+			content:      "grpcServers:\n  - port: 50051\n    fault:\n      percentage: 50\n      types: [explode]\n",
+			expectsError: true,
+		},
 	}
 
 	for _, tc := range tt {
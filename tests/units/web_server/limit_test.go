@@ -0,0 +1,133 @@
+package webserver_test
+
+import (
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(handler http.Handler) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	return rec
+}
+
+func startLimitPlan(t *testing.T, limit *web_server.Limit) {
+	require.NoError(t, limit.Validate())
+
+	plan := limit.MakeInlinePlan()
+	plan.Name = utils.NewP[string]("limit")
+	plan.Assign(limit)
+	t.Cleanup(plan.Stop)
+
+	go plan.Start()
+
+	require.Eventually(t, func() bool { return limit.GetRate() > 0 }, time.Second, 5*time.Millisecond)
+}
+
+func TestLimit(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	t.Run("limits the rate", func(t *testing.T) {
+		limit := &web_server.Limit{Rate: fluent.NewMustFluentSize("2")}
+		startLimitPlan(t, limit)
+
+		handler := limit.Wrap(ok)
+
+		assert.Equal(t, http.StatusOK, serve(handler).Code)
+		assert.Equal(t, http.StatusOK, serve(handler).Code)
+
+		rec := serve(handler)
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+		assert.Eventually(t, func() bool {
+			return serve(handler).Code == http.StatusOK
+		}, time.Second, 50*time.Millisecond, "a token should be available after a while")
+	})
+
+	t.Run("allows bursts", func(t *testing.T) {
+		limit := &web_server.Limit{Rate: fluent.NewMustFluentSize("1"), Burst: utils.NewP[int64](5)}
+		startLimitPlan(t, limit)
+
+		handler := limit.Wrap(ok)
+
+		for i := 0; i < 5; i++ {
+			assert.Equal(t, http.StatusOK, serve(handler).Code)
+		}
+
+		assert.Equal(t, http.StatusTooManyRequests, serve(handler).Code)
+	})
+
+	t.Run("limits the concurrency", func(t *testing.T) {
+		limit := &web_server.Limit{Concurrency: utils.NewP[int64](1)}
+		require.NoError(t, limit.Validate())
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		done := make(chan struct{})
+
+		handler := limit.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		}))
+
+		go func() {
+			defer close(done)
+			serve(handler)
+		}()
+
+		<-started
+
+		rec := serve(handler)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+		close(release)
+		<-done
+
+		assert.Equal(t, http.StatusOK, serve(limit.Wrap(ok)).Code)
+	})
+
+	t.Run("rejects with custom status", func(t *testing.T) {
+		limit := &web_server.Limit{Rate: fluent.NewMustFluentSize("1"), Status: utils.NewP(http.StatusServiceUnavailable)}
+		startLimitPlan(t, limit)
+
+		handler := limit.Wrap(ok)
+
+		assert.Equal(t, http.StatusOK, serve(handler).Code)
+		assert.Equal(t, http.StatusServiceUnavailable, serve(handler).Code)
+	})
+
+	t.Run("validates", func(t *testing.T) {
+		tt := []struct {
+			name  string
+			limit *web_server.Limit
+			err   string
+		}{
+			{name: "nothing is set", limit: &web_server.Limit{}, err: "no rate"},
+			{name: "non-positive concurrency", limit: &web_server.Limit{Concurrency: utils.NewP[int64](0)}, err: "concurrency"},
+			{name: "non-positive burst", limit: &web_server.Limit{Rate: fluent.NewMustFluentSize("1"), Burst: utils.NewP[int64](0)}, err: "burst"},
+			{name: "invalid status", limit: &web_server.Limit{Concurrency: utils.NewP[int64](1), Status: utils.NewP(700)}, err: "status"},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				err := tc.limit.Validate()
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+			})
+		}
+	})
+}
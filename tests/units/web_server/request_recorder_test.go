@@ -0,0 +1,169 @@
+package webserver_test
+
+import (
+	"encoding/json"
+	"io"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getRecords(t *testing.T, url string) []web_server.RecordedRequest {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var records []web_server.RecordedRequest
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&records))
+
+	return records
+}
+
+func TestRequestRecorder(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	ws := &web_server.WebServer{
+		Interface: utils.NewP[string]("127.0.0.1"),
+		Port:      utils.NewP[int32](8414),
+		Recorder: &web_server.RequestRecorder{
+			Path:        "/recorded",
+			Size:        utils.NewP(3),
+			MaxBodySize: fluent.NewMustFluentSize("4"),
+		},
+		Routes: []*web_server.Route{
+			{
+				Path:    "/echo",
+				Methods: []string{"POST"},
+				Content: web_server.RouteContent{Template: "{{ .Body }}"},
+			},
+			{
+				Path: "/faulty",
+				Fault: &web_server.RouteFault{
+					Percentage: *fluent.NewMustFluentFloat("100"),
+					Responses:  []*web_server.FaultResponse{{Status: http.StatusServiceUnavailable}},
+				},
+			},
+		},
+	}
+
+	for _, route := range ws.Routes {
+		require.NoError(t, route.Validate())
+
+		if route.HasInlinePlan() {
+			plan := route.MakeInlinePlan()
+			plan.Name = utils.NewP[string]("recorder-fault")
+			plan.Assign(route)
+			t.Cleanup(plan.Stop)
+
+			go plan.Start()
+		}
+	}
+
+	require.NoError(t, ws.ListenOnBackground())
+	t.Cleanup(func() { ws.Stop() })
+
+	// Wait for the server to accept connections and the fault plan to kick in
+	require.Eventually(t, func() bool {
+		resp, err := http.Get("http://127.0.0.1:8414/faulty")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+
+		return resp.StatusCode == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	clear := func() {
+		resp, err := http.Post("http://127.0.0.1:8414/recorded/clear", "", nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	}
+
+	t.Run("records the requests", func(t *testing.T) {
+		clear()
+
+		req, err := http.NewRequest(http.MethodPost, "http://127.0.0.1:8414/echo?q=kermoo", strings.NewReader("hello world"))
+		require.NoError(t, err)
+		req.Header.Set("X-Test", "header-value")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "hello world", string(body), "the route should receive the whole body")
+
+		records := getRecords(t, "http://127.0.0.1:8414/recorded")
+		require.Len(t, records, 1)
+
+		assert.Equal(t, http.MethodPost, records[0].Method)
+		assert.Equal(t, "/echo", records[0].Path)
+		assert.Equal(t, []string{"kermoo"}, records[0].Query["q"])
+		assert.Equal(t, []string{"header-value"}, records[0].Headers["X-Test"])
+		assert.Equal(t, "hell", records[0].Body)
+		assert.True(t, records[0].BodyTruncated)
+		assert.Equal(t, http.StatusOK, records[0].Status)
+		assert.False(t, records[0].Faulted)
+		assert.WithinDuration(t, time.Now(), records[0].Time, time.Second)
+	})
+
+	t.Run("records the faults", func(t *testing.T) {
+		clear()
+
+		resp, err := http.Get("http://127.0.0.1:8414/faulty")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		records := getRecords(t, "http://127.0.0.1:8414/recorded")
+		require.Len(t, records, 1)
+
+		assert.Equal(t, http.StatusServiceUnavailable, records[0].Status)
+		assert.True(t, records[0].Faulted)
+	})
+
+	t.Run("keeps the latest requests", func(t *testing.T) {
+		clear()
+
+		for _, path := range []string{"/a", "/b", "/c", "/d"} {
+			resp, err := http.Get("http://127.0.0.1:8414" + path)
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+
+		records := getRecords(t, "http://127.0.0.1:8414/recorded")
+		require.Len(t, records, 3)
+
+		assert.Equal(t, "/b", records[0].Path)
+		assert.Equal(t, "/d", records[2].Path)
+		assert.Equal(t, http.StatusNotFound, records[2].Status)
+	})
+
+	t.Run("clears the requests", func(t *testing.T) {
+		resp, err := http.Get("http://127.0.0.1:8414/a")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		clear()
+
+		assert.Empty(t, getRecords(t, "http://127.0.0.1:8414/recorded"))
+	})
+}
+
+func TestRequestRecorderValidation(t *testing.T) {
+	assert.NoError(t, (&web_server.RequestRecorder{}).Validate())
+	assert.Error(t, (&web_server.RequestRecorder{Path: "requests"}).Validate())
+	assert.Error(t, (&web_server.RequestRecorder{Size: utils.NewP(0)}).Validate())
+}
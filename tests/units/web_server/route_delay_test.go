@@ -0,0 +1,177 @@
+package webserver_test
+
+import (
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/planner"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelayDistribution(t *testing.T) {
+	tt := []struct {
+		name string
+		kind string
+	}{
+		{name: "normal distribution", kind: web_server.DELAY_DISTRIBUTION_NORMAL},
+		{name: "exponential distribution", kind: web_server.DELAY_DISTRIBUTION_EXPONENTIAL},
+		{name: "pareto distribution", kind: web_server.DELAY_DISTRIBUTION_PARETO},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dd := web_server.DelayDistribution{
+				Type: tc.kind,
+				P50:  *fluent.NewMustFluentDuration("20ms"),
+				P99:  *fluent.NewMustFluentDuration("200ms"),
+			}
+
+			require.NoError(t, dd.Validate())
+
+			samples := []time.Duration{}
+			for i := 0; i < 20000; i++ {
+				samples = append(samples, dd.Sample())
+			}
+
+			sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+			assert.InDelta(t, float64(20*time.Millisecond), float64(samples[10000]), float64(4*time.Millisecond))
+			assert.InDelta(t, float64(200*time.Millisecond), float64(samples[19800]), float64(40*time.Millisecond))
+		})
+	}
+
+	t.Run("caps the delays by max", func(t *testing.T) {
+		dd := web_server.DelayDistribution{
+			Type: web_server.DELAY_DISTRIBUTION_PARETO,
+			P50:  *fluent.NewMustFluentDuration("20ms"),
+			P99:  *fluent.NewMustFluentDuration("2s"),
+			Max:  fluent.NewMustFluentDuration("100ms"),
+		}
+
+		for i := 0; i < 1000; i++ {
+			assert.LessOrEqual(t, dd.Sample(), 100*time.Millisecond)
+		}
+	})
+
+	t.Run("fails with invalid specifications", func(t *testing.T) {
+		invalids := []web_server.DelayDistribution{
+			{Type: "weird", P50: *fluent.NewMustFluentDuration("1ms"), P99: *fluent.NewMustFluentDuration("2ms")},
+			{Type: web_server.DELAY_DISTRIBUTION_NORMAL, P50: *fluent.NewMustFluentDuration("1ms")},
+			{Type: web_server.DELAY_DISTRIBUTION_NORMAL, P50: *fluent.NewMustFluentDuration("2ms"), P99: *fluent.NewMustFluentDuration("1ms")},
+			{Type: web_server.DELAY_DISTRIBUTION_PARETO, P50: *fluent.NewMustFluentDuration("0ms"), P99: *fluent.NewMustFluentDuration("1ms")},
+			{Type: web_server.DELAY_DISTRIBUTION_NORMAL, P50: *fluent.NewMustFluentDuration("1ms to 5ms"), P99: *fluent.NewMustFluentDuration("4ms")},
+			{Type: web_server.DELAY_DISTRIBUTION_NORMAL, P50: *fluent.NewMustFluentDuration("1ms"), P99: *fluent.NewMustFluentDuration("2ms, 4ms")},
+			{Type: web_server.DELAY_DISTRIBUTION_NORMAL, P50: *fluent.NewMustFluentDuration("1ms"), P99: *fluent.NewMustFluentDuration("2ms"), Max: fluent.NewMustFluentDuration("3ms to 5ms")},
+		}
+
+		for _, dd := range invalids {
+			assert.Error(t, dd.Validate())
+		}
+	})
+}
+
+func TestRouteResponseDelay(t *testing.T) {
+	t.Run("delays successful responses", func(t *testing.T) {
+		route := web_server.Route{
+			Path: "/delayed",
+			Fault: &web_server.RouteFault{
+				ResponseDelay: fluent.NewMustFluentDuration("50ms to 60ms"),
+			},
+		}
+
+		require.NoError(t, route.Validate())
+
+		startedAt := time.Now()
+		w := httptest.NewRecorder()
+		route.Handle(w, httptest.NewRequest("GET", "/delayed", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.GreaterOrEqual(t, time.Since(startedAt), 50*time.Millisecond)
+	})
+
+	t.Run("does not delay successful responses when only faults are delayed", func(t *testing.T) {
+		route := web_server.Route{
+			Path: "/delayed",
+			Fault: &web_server.RouteFault{
+				ResponseDelay:   fluent.NewMustFluentDuration("1s"),
+				DelayFaultsOnly: utils.NewP(true),
+			},
+		}
+
+		assert.False(t, route.Fault.ShouldDelay(false))
+		assert.True(t, route.Fault.ShouldDelay(true))
+		assert.Equal(t, time.Second, route.Fault.GetResponseDelay())
+	})
+
+	t.Run("fails when both delay and distribution are set", func(t *testing.T) {
+		route := web_server.Route{
+			Path: "/delayed",
+			Fault: &web_server.RouteFault{
+				ResponseDelay: fluent.NewMustFluentDuration("1s"),
+				ResponseDelayDistribution: &web_server.DelayDistribution{
+					Type: web_server.DELAY_DISTRIBUTION_NORMAL,
+					P50:  *fluent.NewMustFluentDuration("1ms"),
+					P99:  *fluent.NewMustFluentDuration("2ms"),
+				},
+			},
+		}
+
+		assert.Error(t, route.Validate())
+	})
+
+	t.Run("makes a never-failing plan when only delay is set", func(t *testing.T) {
+		route := web_server.Route{
+			Path: "/delayed",
+			Fault: &web_server.RouteFault{
+				ResponseDelay: fluent.NewMustFluentDuration("1s"),
+			},
+		}
+
+		plan := route.MakeInlinePlan()
+		assert.Equal(t, float64(0), plan.Percentage.Get())
+	})
+}
+
+func TestRoutePlanLatency(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	planName := "latency"
+
+	plan := planner.NewPlan(planner.Plan{
+		Name:     &planName,
+		Interval: fluent.NewMustFluentDuration("1h"),
+		Latency:  fluent.NewMustFluentDuration("60ms"),
+	})
+
+	route := web_server.Route{
+		Path: "/delayed",
+		Fault: &web_server.RouteFault{
+			PlanRefs: []string{planName},
+		},
+	}
+
+	require.NoError(t, plan.Validate())
+
+	plan.Assign(&route)
+	go plan.Start()
+
+	// Give plan a moment to start its first cycle
+	for plan.GetCurrentValue() == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	startedAt := time.Now()
+	w := httptest.NewRecorder()
+	route.Handle(w, httptest.NewRequest("GET", "/delayed", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.GreaterOrEqual(t, time.Since(startedAt), 60*time.Millisecond)
+}
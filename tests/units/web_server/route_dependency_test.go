@@ -0,0 +1,91 @@
+package webserver_test
+
+import (
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/memory"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteDependencies(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	serve := func(route *web_server.Route) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		route.Handle(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+		return w
+	}
+
+	t.Run("unhealthy while memory leak is above threshold", func(t *testing.T) {
+		leak := &memory.MemoryLeak{}
+		route := &web_server.Route{
+			Path:      "/readyz",
+			DependsOn: &web_server.RouteDependencies{MemoryLeakAbove: fluent.NewMustFluentSize("1Ki")},
+		}
+
+		require.NoError(t, route.DependsOn.Bind(leak, nil, nil, nil))
+
+		leak.StartLeaking(512)
+		assert.Equal(t, http.StatusOK, serve(route).Code)
+
+		leak.StartLeaking(2048)
+		w := serve(route)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "memory leak of 2048 bytes is above 1024 bytes")
+
+		leak.StopLeaking()
+		assert.Equal(t, http.StatusOK, serve(route).Code)
+	})
+
+	t.Run("unhealthy while webserver is down", func(t *testing.T) {
+		ws := &web_server.WebServer{
+			Interface: utils.NewP[string]("127.0.0.1"),
+			Port:      utils.NewP[int32](8416),
+		}
+		route := &web_server.Route{
+			Path:      "/readyz",
+			DependsOn: &web_server.RouteDependencies{WebServers: []string{ws.GetName()}},
+		}
+
+		require.NoError(t, route.DependsOn.Bind(nil, nil, []*web_server.WebServer{ws}, nil))
+
+		w := serve(route)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Contains(t, w.Body.String(), "webserver webserver-127-0-0-1-8416 is down")
+
+		require.NoError(t, ws.ListenOnBackground())
+		defer ws.Stop()
+
+		assert.Eventually(t, func() bool {
+			return serve(route).Code == http.StatusOK
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("binding to missing components fails", func(t *testing.T) {
+		deps := &web_server.RouteDependencies{MemoryLeakAbove: fluent.NewMustFluentSize("1Ki")}
+		assert.Error(t, deps.Bind(nil, nil, nil, nil))
+
+		deps = &web_server.RouteDependencies{CpuLoadAbove: fluent.NewMustFluentFloat("80")}
+		assert.Error(t, deps.Bind(nil, nil, nil, nil))
+
+		deps = &web_server.RouteDependencies{WebServers: []string{"webserver-unknown"}}
+		assert.Error(t, deps.Bind(nil, nil, nil, nil))
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		assert.Error(t, (&web_server.RouteDependencies{CpuLoadAbove: fluent.NewMustFluentFloat("120")}).Validate())
+		assert.Error(t, (&web_server.RouteDependencies{TcpServers: []string{""}}).Validate())
+		assert.Error(t, (&web_server.RouteDependencies{CpuLoadAbove: fluent.NewMustFluentFloat("40 to 80")}).Validate())
+		assert.Error(t, (&web_server.RouteDependencies{MemoryLeakAbove: fluent.NewMustFluentSize("1Mi, 2Mi")}).Validate())
+		assert.NoError(t, (&web_server.RouteDependencies{CpuLoadAbove: fluent.NewMustFluentFloat("80")}).Validate())
+	})
+}
@@ -0,0 +1,204 @@
+package webserver_test
+
+import (
+	"context"
+	"io"
+	"kermoo/modules/fluent"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteFaultResponses(t *testing.T) {
+	t.Run("serves random server errors by default", func(t *testing.T) {
+		fault := &web_server.RouteFault{}
+		require.NoError(t, fault.Validate())
+
+		rec := httptest.NewRecorder()
+		fault.Handle(rec, httptest.NewRequest(http.MethodGet, "/default", nil), "", "/default")
+
+		assert.GreaterOrEqual(t, rec.Code, 500)
+		assert.Equal(t, http.StatusText(rec.Code), rec.Body.String())
+	})
+
+	t.Run("serves custom body and headers", func(t *testing.T) {
+		fault := &web_server.RouteFault{
+			Responses: []*web_server.FaultResponse{
+				{
+					Status:  http.StatusTooManyRequests,
+					Body:    `{"error": "{{ .StatusText }}", "path": "{{ .Path }}"}`,
+					Headers: map[string]string{"Retry-After": "30", "Content-Type": "application/json"},
+				},
+			},
+		}
+		require.NoError(t, fault.Validate())
+
+		rec := httptest.NewRecorder()
+		fault.Handle(rec, httptest.NewRequest(http.MethodGet, "/api", nil), "", "/api")
+
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.JSONEq(t, `{"error": "Too Many Requests", "path": "/api"}`, rec.Body.String())
+		assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("picks responses by weight", func(t *testing.T) {
+		fault := &web_server.RouteFault{
+			Responses: []*web_server.FaultResponse{
+				{Status: http.StatusServiceUnavailable, Weight: utils.NewP(70.0)},
+				{Status: http.StatusTooManyRequests, Weight: utils.NewP(30.0)},
+			},
+		}
+		require.NoError(t, fault.Validate())
+
+		codes := map[int]int{}
+		for i := 0; i < 2000; i++ {
+			rec := httptest.NewRecorder()
+			fault.Handle(rec, httptest.NewRequest(http.MethodGet, "/weighted", nil), "", "/weighted")
+			codes[rec.Code]++
+		}
+
+		assert.Len(t, codes, 2)
+		assert.InDelta(t, 1400, codes[http.StatusServiceUnavailable], 150)
+		assert.InDelta(t, 600, codes[http.StatusTooManyRequests], 150)
+	})
+
+	t.Run("serves internal server error when body can not be rendered", func(t *testing.T) {
+		fault := &web_server.RouteFault{
+			Responses: []*web_server.FaultResponse{{Status: http.StatusServiceUnavailable, Body: "{{ .Unknown }}"}},
+		}
+
+		rec := httptest.NewRecorder()
+		fault.Handle(rec, httptest.NewRequest(http.MethodGet, "/broken", nil), "", "/broken")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Contains(t, rec.Body.String(), "unable to render fault response")
+	})
+
+	t.Run("validates responses", func(t *testing.T) {
+		tt := []struct {
+			name  string
+			fault web_server.RouteFault
+			err   string
+		}{
+			{
+				name:  "invalid status",
+				fault: web_server.RouteFault{Responses: []*web_server.FaultResponse{{Status: 700}}},
+				err:   "not a valid HTTP status code",
+			},
+			{
+				name:  "non-positive weight",
+				fault: web_server.RouteFault{Responses: []*web_server.FaultResponse{{Status: 503, Weight: utils.NewP(0.0)}}},
+				err:   "weight",
+			},
+			{
+				name:  "invalid template",
+				fault: web_server.RouteFault{Responses: []*web_server.FaultResponse{{Status: 503, Body: "{{ .Status"}}},
+				err:   "not a valid template",
+			},
+			{
+				name:  "template failing on render",
+				fault: web_server.RouteFault{Responses: []*web_server.FaultResponse{{Status: 503, Body: "{{ .Unknown }}"}}},
+				err:   "not a valid template",
+			},
+			{
+				name:  "unknown kind",
+				fault: web_server.RouteFault{Responses: []*web_server.FaultResponse{{Kind: "explode"}}},
+				err:   "explode",
+			},
+			{
+				name: "responses along with server errors",
+				fault: web_server.RouteFault{
+					Responses:    []*web_server.FaultResponse{{Status: 503}},
+					ServerErrors: utils.NewP(true),
+				},
+				err: "can not be used along with",
+			},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.name, func(t *testing.T) {
+				err := tc.fault.Validate()
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+			})
+		}
+	})
+}
+
+func startFaultServer(t *testing.T, response *web_server.FaultResponse) *httptest.Server {
+	fault := &web_server.RouteFault{Responses: []*web_server.FaultResponse{response}}
+	require.NoError(t, fault.Validate())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fault.Handle(w, r, "", "/conn")
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestRouteFaultConnectionKinds(t *testing.T) {
+	t.Run("resets the connection", func(t *testing.T) {
+		server := startFaultServer(t, &web_server.FaultResponse{Kind: web_server.FAULT_KIND_RESET})
+
+		_, err := http.Get(server.URL)
+		require.Error(t, err)
+	})
+
+	t.Run("hangs after sending the headers", func(t *testing.T) {
+		server := startFaultServer(t, &web_server.FaultResponse{Kind: web_server.FAULT_KIND_HANG})
+		client := &http.Client{Timeout: 200 * time.Millisecond}
+
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		_, err = io.ReadAll(resp.Body)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("sends a truncated body", func(t *testing.T) {
+		server := startFaultServer(t, &web_server.FaultResponse{Kind: web_server.FAULT_KIND_PARTIAL, Body: "abcdefgh"})
+
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, int64(8), resp.ContentLength)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+		assert.Equal(t, "abcd", string(body))
+	})
+
+	t.Run("drips the body", func(t *testing.T) {
+		server := startFaultServer(t, &web_server.FaultResponse{
+			Kind:         web_server.FAULT_KIND_DRIP,
+			Status:       http.StatusServiceUnavailable,
+			Body:         "abcd",
+			DripInterval: fluent.NewMustFluentDuration("50ms"),
+		})
+
+		start := time.Now()
+
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, "abcd", string(body))
+		assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	})
+}
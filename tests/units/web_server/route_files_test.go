@@ -0,0 +1,116 @@
+package webserver_test
+
+import (
+	"io"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, url string, headers map[string]string) (*http.Response, string) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, string(body)
+}
+
+func TestRouteFiles(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("Hello, Kermoo!"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nested", "deep.txt"), []byte("deep"), 0644))
+
+	ws := &web_server.WebServer{
+		Interface: utils.NewP[string]("127.0.0.1"),
+		Port:      utils.NewP[int32](8412),
+		Routes: []*web_server.Route{
+			{Path: "/file", Content: web_server.RouteContent{File: filepath.Join(dir, "hello.txt")}},
+			{Path: "/files/", Content: web_server.RouteContent{Directory: dir}},
+			{Path: "/payload", Content: web_server.RouteContent{RandomPayload: fluent.NewMustFluentSize("1Mi")}},
+		},
+	}
+
+	for _, route := range ws.Routes {
+		require.NoError(t, route.Validate())
+	}
+
+	require.NoError(t, ws.ListenOnBackground())
+	t.Cleanup(func() { ws.Stop() })
+
+	// Give server a moment to start
+	time.Sleep(100 * time.Millisecond)
+
+	t.Run("serves file", func(t *testing.T) {
+		resp, body := get(t, "http://127.0.0.1:8412/file", nil)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "Hello, Kermoo!", body)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+		require.NotEmpty(t, resp.Header.Get("ETag"))
+
+		resp, _ = get(t, "http://127.0.0.1:8412/file", map[string]string{"If-None-Match": resp.Header.Get("ETag")})
+		assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	})
+
+	t.Run("serves range of file", func(t *testing.T) {
+		resp, body := get(t, "http://127.0.0.1:8412/file", map[string]string{"Range": "bytes=7-12"})
+
+		assert.Equal(t, http.StatusPartialContent, resp.StatusCode)
+		assert.Equal(t, "Kermoo", body)
+	})
+
+	t.Run("serves directory tree", func(t *testing.T) {
+		resp, body := get(t, "http://127.0.0.1:8412/files/nested/deep.txt", nil)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "deep", body)
+
+		resp, _ = get(t, "http://127.0.0.1:8412/files/missing.txt", nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("serves random payload", func(t *testing.T) {
+		resp, body := get(t, "http://127.0.0.1:8412/payload", nil)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+		assert.Equal(t, int64(1024*1024), resp.ContentLength)
+		assert.Len(t, body, 1024*1024)
+	})
+
+	t.Run("validates", func(t *testing.T) {
+		err := (&web_server.RouteContent{File: dir}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is a directory")
+
+		err = (&web_server.RouteContent{Directory: filepath.Join(dir, "hello.txt")}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "not a directory")
+
+		err = (&web_server.RouteContent{File: filepath.Join(dir, "hello.txt"), Static: "hi"}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "only one of")
+	})
+}
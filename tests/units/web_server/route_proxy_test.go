@@ -0,0 +1,107 @@
+package webserver_test
+
+import (
+	"fmt"
+	"io"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteProxy(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	var hits atomic.Int64
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("X-Upstream", "yes")
+		fmt.Fprintf(w, "%s %s?%s %s", r.Method, r.URL.Path, r.URL.RawQuery, body)
+	}))
+	t.Cleanup(upstream.Close)
+
+	ws := &web_server.WebServer{
+		Interface: utils.NewP[string]("127.0.0.1"),
+		Port:      utils.NewP[int32](8413),
+		Routes: []*web_server.Route{
+			{Path: "/api/", Content: web_server.RouteContent{Proxy: upstream.URL + "/base"}},
+			{Path: "/down/", Content: web_server.RouteContent{Proxy: "http://127.0.0.1:1"}},
+			{
+				Path:    "/faulty/",
+				Content: web_server.RouteContent{Proxy: upstream.URL},
+				Fault: &web_server.RouteFault{
+					Percentage: *fluent.NewMustFluentFloat("100"),
+					Responses:  []*web_server.FaultResponse{{Status: http.StatusServiceUnavailable}},
+				},
+			},
+		},
+	}
+
+	for _, route := range ws.Routes {
+		require.NoError(t, route.Validate())
+
+		if route.HasInlinePlan() {
+			plan := route.MakeInlinePlan()
+			plan.Name = utils.NewP[string]("proxy-fault")
+			plan.Assign(route)
+			t.Cleanup(plan.Stop)
+
+			go plan.Start()
+		}
+	}
+
+	require.NoError(t, ws.ListenOnBackground())
+	t.Cleanup(func() { ws.Stop() })
+
+	// Give server and plans a moment to start
+	time.Sleep(100 * time.Millisecond)
+
+	t.Run("forwards requests to upstream", func(t *testing.T) {
+		resp, err := http.Post("http://127.0.0.1:8413/api/users?id=1", "text/plain", strings.NewReader("hello"))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "yes", resp.Header.Get("X-Upstream"))
+		assert.Equal(t, "POST /base/api/users?id=1 hello", string(body))
+	})
+
+	t.Run("responds bad gateway when upstream is down", func(t *testing.T) {
+		resp, err := http.Get("http://127.0.0.1:8413/down/")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	})
+
+	t.Run("applies faults in front of upstream", func(t *testing.T) {
+		before := hits.Load()
+
+		resp, err := http.Get("http://127.0.0.1:8413/faulty/anything")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, before, hits.Load(), "upstream should not be hit")
+	})
+
+	t.Run("validates upstream", func(t *testing.T) {
+		err := (&web_server.RouteContent{Proxy: "ftp://example.com"}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "scheme")
+	})
+}
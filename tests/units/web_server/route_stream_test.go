@@ -0,0 +1,344 @@
+package webserver_test
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"kermoo/modules/fluent"
+	"kermoo/modules/logger"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeStreamRoute(path string, content web_server.RouteContent, faultTypes ...string) *web_server.Route {
+	route := &web_server.Route{Path: path, Content: content}
+
+	if len(faultTypes) > 0 {
+		route.Fault = &web_server.RouteFault{
+			Percentage: *fluent.NewMustFluentFloat("100"),
+			Stream:     &web_server.StreamFault{Types: faultTypes},
+		}
+	}
+
+	return route
+}
+
+func startStreamServer(t *testing.T) *web_server.WebServer {
+	sse := func() web_server.RouteContent {
+		return web_server.RouteContent{Sse: &web_server.SseContent{
+			Interval: fluent.NewMustFluentDuration("50ms"),
+			Event:    "tick",
+			Data:     `{"id": {{ .Id }}}`,
+		}}
+	}
+
+	websocket := func() web_server.RouteContent {
+		return web_server.RouteContent{WebSocket: &web_server.WebSocketContent{
+			Heartbeat: fluent.NewMustFluentDuration("50ms"),
+		}}
+	}
+
+	ws := &web_server.WebServer{
+		Interface: utils.NewP[string]("127.0.0.1"),
+		Port:      utils.NewP[int32](8415),
+		Routes: []*web_server.Route{
+			makeStreamRoute("/sse", sse()),
+			makeStreamRoute("/sse/drop", sse(), web_server.STREAM_FAULT_DROP),
+			makeStreamRoute("/sse/silence", sse(), web_server.STREAM_FAULT_SILENCE),
+			makeStreamRoute("/sse/malformed", sse(), web_server.STREAM_FAULT_MALFORMED),
+			makeStreamRoute("/sse/idle/malformed", web_server.RouteContent{Sse: &web_server.SseContent{
+				Interval:  fluent.NewMustFluentDuration("1h"),
+				Heartbeat: fluent.NewMustFluentDuration("50ms"),
+			}}, web_server.STREAM_FAULT_MALFORMED),
+			makeStreamRoute("/ws", websocket()),
+			makeStreamRoute("/ws/drop", websocket(), web_server.STREAM_FAULT_DROP),
+			makeStreamRoute("/ws/silence", websocket(), web_server.STREAM_FAULT_SILENCE),
+			makeStreamRoute("/ws/malformed", websocket(), web_server.STREAM_FAULT_MALFORMED),
+		},
+	}
+
+	for _, route := range ws.Routes {
+		require.NoError(t, route.Validate())
+
+		if route.HasInlinePlan() {
+			plan := route.MakeInlinePlan()
+			plan.Name = utils.NewP[string]("stream-fault")
+			plan.Assign(route)
+			t.Cleanup(plan.Stop)
+
+			go plan.Start()
+		}
+	}
+
+	require.NoError(t, ws.ListenOnBackground())
+	t.Cleanup(func() { ws.Stop() })
+
+	// Give server and plans a moment to start
+	time.Sleep(100 * time.Millisecond)
+
+	return ws
+}
+
+func openSse(t *testing.T, path string) *bufio.Reader {
+	resp, err := http.Get("http://127.0.0.1:8415" + path)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	return bufio.NewReader(resp.Body)
+}
+
+// readSseEvent reads the lines of an event, up to the blank line.
+func readSseEvent(r *bufio.Reader) ([]string, error) {
+	lines := []string{}
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return lines, err
+		}
+
+		if line == "\n" {
+			return lines, nil
+		}
+
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+}
+
+type wsClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, path string) *wsClient {
+	conn, err := net.Dial("tcp", "127.0.0.1:8415")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: 127.0.0.1\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", path)
+
+	reader := bufio.NewReader(conn)
+
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	return &wsClient{conn: conn, reader: reader}
+}
+
+func (c *wsClient) send(opcode byte, payload string) error {
+	mask := make([]byte, 4)
+	_, _ = rand.Read(mask)
+
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+
+	for i := range payload {
+		frame = append(frame, payload[i]^mask[i%4])
+	}
+
+	_, err := c.conn.Write(frame)
+
+	return err
+}
+
+// receive reads a frame sent by the server, which is unmasked and small in the tests.
+func (c *wsClient) receive(timeout time.Duration) (byte, string, error) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(timeout))
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(c.reader, header); err != nil {
+		return 0, "", err
+	}
+
+	length := int(header[1] & 0x7F)
+	if length == 126 {
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(c.reader, extended); err != nil {
+			return 0, "", err
+		}
+
+		length = int(binary.BigEndian.Uint16(extended))
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return 0, "", err
+	}
+
+	return header[0], string(payload), nil
+}
+
+// receiveMessage skips the pings and returns the first message.
+func (c *wsClient) receiveMessage(t *testing.T) (byte, string) {
+	for {
+		header, payload, err := c.receive(time.Second)
+		require.NoError(t, err)
+
+		if header&0x0F != web_server.WEBSOCKET_OPCODE_PING {
+			return header, payload
+		}
+	}
+}
+
+func TestRouteSse(t *testing.T) {
+	logger.MustInitLogger("fatal")
+	startStreamServer(t)
+
+	t.Run("emits events", func(t *testing.T) {
+		reader := openSse(t, "/sse")
+
+		for id := 1; id <= 3; id++ {
+			lines, err := readSseEvent(reader)
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{fmt.Sprintf("id: %d", id), "event: tick", fmt.Sprintf(`data: {"id": %d}`, id)}, lines)
+		}
+	})
+
+	t.Run("drops the stream", func(t *testing.T) {
+		reader := openSse(t, "/sse/drop")
+
+		_, err := readSseEvent(reader)
+		assert.Error(t, err)
+	})
+
+	t.Run("goes silent", func(t *testing.T) {
+		resp, err := (&http.Client{Timeout: 300 * time.Millisecond}).Get("http://127.0.0.1:8415/sse/silence")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		assert.Error(t, err, "the stream should be kept open")
+		assert.Empty(t, body)
+	})
+
+	t.Run("sends malformed events", func(t *testing.T) {
+		reader := openSse(t, "/sse/malformed")
+
+		lines, err := readSseEvent(reader)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"id: \xff\xfe", `data: {"id":`}, lines)
+	})
+
+	t.Run("applies faults on heartbeats", func(t *testing.T) {
+		reader := openSse(t, "/sse/idle/malformed")
+
+		lines, err := readSseEvent(reader)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"id: \xff\xfe", `data: {"id":`}, lines)
+	})
+}
+
+func TestRouteWebSocket(t *testing.T) {
+	logger.MustInitLogger("fatal")
+	ws := startStreamServer(t)
+
+	t.Run("echoes messages", func(t *testing.T) {
+		client := dialWebSocket(t, "/ws")
+
+		require.NoError(t, client.send(web_server.WEBSOCKET_OPCODE_TEXT, "hello"))
+
+		header, payload := client.receiveMessage(t)
+		assert.Equal(t, byte(0x80|web_server.WEBSOCKET_OPCODE_TEXT), header)
+		assert.Equal(t, "hello", payload)
+
+		require.NoError(t, client.send(web_server.WEBSOCKET_OPCODE_PING, "ping"))
+
+		header, payload = client.receiveMessage(t)
+		assert.Equal(t, byte(0x80|web_server.WEBSOCKET_OPCODE_PONG), header)
+		assert.Equal(t, "ping", payload)
+
+		require.NoError(t, client.send(web_server.WEBSOCKET_OPCODE_CLOSE, "\x03\xe8"))
+
+		header, _ = client.receiveMessage(t)
+		assert.Equal(t, byte(0x80|web_server.WEBSOCKET_OPCODE_CLOSE), header)
+	})
+
+	t.Run("sends heartbeats", func(t *testing.T) {
+		client := dialWebSocket(t, "/ws")
+
+		header, _, err := client.receive(time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, byte(0x80|web_server.WEBSOCKET_OPCODE_PING), header)
+	})
+
+	t.Run("rejects plain requests", func(t *testing.T) {
+		resp, err := http.Get("http://127.0.0.1:8415/ws")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusUpgradeRequired, resp.StatusCode)
+	})
+
+	t.Run("drops the connection", func(t *testing.T) {
+		client := dialWebSocket(t, "/ws/drop")
+
+		_, _, err := client.receive(time.Second)
+		assert.Error(t, err)
+	})
+
+	t.Run("goes silent", func(t *testing.T) {
+		client := dialWebSocket(t, "/ws/silence")
+
+		require.NoError(t, client.send(web_server.WEBSOCKET_OPCODE_TEXT, "hello"))
+
+		_, _, err := client.receive(300 * time.Millisecond)
+
+		var netErr net.Error
+		require.ErrorAs(t, err, &netErr)
+		assert.True(t, netErr.Timeout(), "the connection should be kept open")
+	})
+
+	t.Run("sends malformed frames", func(t *testing.T) {
+		client := dialWebSocket(t, "/ws/malformed")
+
+		header, _, err := client.receive(time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, byte(0xF3), header)
+	})
+
+	t.Run("closes the connections on stop", func(t *testing.T) {
+		client := dialWebSocket(t, "/ws/silence")
+
+		require.NoError(t, ws.Stop())
+
+		_, _, err := client.receive(time.Second)
+		require.Error(t, err)
+
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			assert.False(t, netErr.Timeout(), "the connection should be closed rather than kept open")
+		}
+	})
+}
+
+func TestStreamFaultValidation(t *testing.T) {
+	route := makeStreamRoute("/static", web_server.RouteContent{Static: "hello"}, web_server.STREAM_FAULT_DROP)
+	assert.ErrorContains(t, route.Validate(), "stream faults")
+
+	route = makeStreamRoute("/sse", web_server.RouteContent{Sse: &web_server.SseContent{}}, "explode")
+	assert.ErrorContains(t, route.Validate(), "not supported")
+
+	route = makeStreamRoute("/both", web_server.RouteContent{Sse: &web_server.SseContent{}, WebSocket: &web_server.WebSocketContent{}})
+	assert.ErrorContains(t, route.Validate(), "only one of")
+}
@@ -0,0 +1,95 @@
+package webserver_test
+
+import (
+	"io"
+	"kermoo/modules/logger"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteTemplate(t *testing.T) {
+	logger.MustInitLogger("fatal")
+	t.Setenv("KERMOO_TEMPLATE_TEST", "from-env")
+
+	ws := &web_server.WebServer{
+		Interface: utils.NewP[string]("127.0.0.1"),
+		Port:      utils.NewP[int32](8411),
+		Routes: []*web_server.Route{
+			{
+				Path:    "/users/{id}",
+				Methods: []string{"POST"},
+				Content: web_server.RouteContent{
+					Template: `{"id": {{ json .Params.id }}, "method": "{{ .Method }}", "q": {{ json (.Query.Get "q") }}, ` +
+						`"header": "{{ .Headers.Get "X-Test" }}", "body": {{ json .Body }}, "env": "{{ .Env.KERMOO_TEMPLATE_TEST }}"}`,
+					ContentType: "application/json",
+					Status:      http.StatusCreated,
+				},
+			},
+			{
+				Path:    "/broken",
+				Content: web_server.RouteContent{Template: `{{ .Missing }}`},
+			},
+		},
+	}
+
+	for _, route := range ws.Routes {
+		require.NoError(t, route.Validate())
+	}
+
+	require.NoError(t, ws.ListenOnBackground())
+	t.Cleanup(func() { ws.Stop() })
+
+	// Give server a moment to start
+	time.Sleep(100 * time.Millisecond)
+
+	t.Run("renders the request", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "http://127.0.0.1:8411/users/42?q=kermoo", strings.NewReader("hello"))
+		require.NoError(t, err)
+		req.Header.Set("X-Test", "header-value")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.JSONEq(t, `{"id": "42", "method": "POST", "q": "kermoo", "header": "header-value", "body": "hello", "env": "from-env"}`, string(body))
+	})
+
+	t.Run("fails on rendering errors", func(t *testing.T) {
+		resp, err := http.Get("http://127.0.0.1:8411/broken")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	})
+}
+
+func TestRouteContentValidate(t *testing.T) {
+	tt := []struct {
+		name    string
+		content web_server.RouteContent
+		err     string
+	}{
+		{name: "template along with static", content: web_server.RouteContent{Template: "hi", Static: "hi"}, err: "only one of"},
+		{name: "invalid template", content: web_server.RouteContent{Template: "{{ .Path"}, err: "template is invalid"},
+		{name: "invalid status", content: web_server.RouteContent{Status: 42}, err: "status"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.content.Validate()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
@@ -98,6 +98,8 @@ func TestRoutes(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, resp.StatusCode)
 		assert.Equal(t, config.BuildVersion, response.Server.KermooVersion)
+		assert.Greater(t, response.Server.Runtime.Goroutines, 0)
+		assert.Greater(t, response.Server.Runtime.Threads, 0)
 		assert.Equal(t, "/info", response.Request.Path)
 	})
 }
@@ -0,0 +1,129 @@
+package webserver_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"kermoo/modules/logger"
+	"kermoo/modules/utils"
+	"kermoo/modules/web_server"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startTlsServer(t *testing.T, port int32, wt *web_server.WebServerTls) (*web_server.WebServer, *http.Client) {
+	wt.ExportCaCert = utils.NewP[string](filepath.Join(t.TempDir(), "ca.pem"))
+
+	ws := &web_server.WebServer{
+		Interface: utils.NewP[string]("127.0.0.1"),
+		Port:      utils.NewP[int32](port),
+		Tls:       wt,
+		Routes: []*web_server.Route{
+			{Path: "/info", Content: web_server.RouteContent{Static: "Hello, TLS!"}},
+		},
+	}
+
+	require.NoError(t, ws.Validate())
+	require.NoError(t, ws.ListenOnBackground())
+	t.Cleanup(func() { ws.Stop() })
+
+	ca, err := os.ReadFile(*wt.ExportCaCert)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(ca))
+
+	client := &http.Client{
+		Timeout: time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{RootCAs: pool},
+			ForceAttemptHTTP2: true,
+			DisableKeepAlives: true,
+		},
+	}
+
+	require.Eventually(t, func() bool {
+		_, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port), &tls.Config{InsecureSkipVerify: true})
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	return ws, client
+}
+
+func TestWebServerTls(t *testing.T) {
+	logger.MustInitLogger("fatal")
+
+	t.Run("serves https over http/2", func(t *testing.T) {
+		_, client := startTlsServer(t, 8401, &web_server.WebServerTls{})
+
+		resp, err := client.Get("https://localhost:8401/info")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, _ := io.ReadAll(resp.Body)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "Hello, TLS!", string(body))
+		assert.Equal(t, 2, resp.ProtoMajor)
+	})
+
+	t.Run("serves https over http/1.1 when http/2 is disabled", func(t *testing.T) {
+		_, client := startTlsServer(t, 8402, &web_server.WebServerTls{Http2: utils.NewP[bool](false)})
+
+		resp, err := client.Get("https://127.0.0.1:8402/info")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 1, resp.ProtoMajor)
+	})
+
+	t.Run("injects certificate faults", func(t *testing.T) {
+		wt := &web_server.WebServerTls{}
+		_, client := startTlsServer(t, 8403, wt)
+
+		tt := []struct {
+			fault string
+			err   string
+		}{
+			{fault: web_server.TLS_FAULT_EXPIRED, err: "expired"},
+			{fault: web_server.TLS_FAULT_WRONG_HOST, err: "not localhost"},
+			{fault: web_server.TLS_FAULT_UNTRUSTED, err: "unknown authority"},
+			{fault: web_server.TLS_FAULT_ABORT, err: "remote error"},
+		}
+
+		for _, tc := range tt {
+			t.Run(tc.fault, func(t *testing.T) {
+				wt.SetFault(tc.fault)
+
+				_, err := client.Get("https://localhost:8403/info")
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.err)
+			})
+		}
+
+		wt.SetFault("")
+
+		resp, err := client.Get("https://localhost:8403/info")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("validates certificate pairs", func(t *testing.T) {
+		err := (&web_server.WebServer{Tls: &web_server.WebServerTls{CertFile: utils.NewP[string]("cert.pem")}}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "together")
+
+		err = (&web_server.WebServer{Tls: &web_server.WebServerTls{Fault: &web_server.TlsFault{Types: []string{"explode"}}}}).Validate()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "explode")
+	})
+}